@@ -0,0 +1,208 @@
+// gormstarter-gen 根据实现 IBaseModel (声明了 TableName 方法) 的结构体及其gorm标签 生成类型安全的字段描述 gormstarter.Column
+//
+// 在模型所在包中声明
+//
+//	//go:generate go run github.com/golang-acexy/starter-gorm/cmd/gormstarter-gen
+//
+// 执行 go generate 后将在该包下生成 columns_gen.go 每个模型对应一个 <Model>Columns 变量
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+const importPath = "github.com/golang-acexy/starter-gorm/gormstarter"
+
+type column struct {
+	field string
+	name  string
+}
+
+type model struct {
+	name    string
+	columns []column
+}
+
+type generator struct {
+	naming  schema.NamingStrategy
+	structs map[string]*ast.StructType
+	models  map[string]bool
+}
+
+func main() {
+	dir := flag.String("dir", ".", "模型所在的包目录")
+	output := flag.String("output", "columns_gen.go", "生成的文件名")
+	flag.Parse()
+	if err := run(*dir, *output); err != nil {
+		fmt.Fprintln(os.Stderr, "gormstarter-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, output string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	fileSet := token.NewFileSet()
+	g := &generator{structs: make(map[string]*ast.StructType), models: make(map[string]bool)}
+	var pkgName string
+	var order []string
+	for _, file := range files {
+		base := filepath.Base(file)
+		if base == output || strings.HasSuffix(base, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fileSet, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		pkgName = f.Name.Name
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					if typeSpec, ok := spec.(*ast.TypeSpec); ok && typeSpec.TypeParams == nil {
+						if structType, ok := typeSpec.Type.(*ast.StructType); ok {
+							g.structs[typeSpec.Name.Name] = structType
+							order = append(order, typeSpec.Name.Name)
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				if d.Recv != nil && d.Name.Name == "TableName" && len(d.Recv.List) == 1 {
+					g.models[receiverName(d.Recv.List[0].Type)] = true
+				}
+			}
+		}
+	}
+	var models []model
+	for _, name := range order {
+		if !g.models[name] {
+			continue
+		}
+		models = append(models, model{name: name, columns: g.columns(g.structs[name], "", "")})
+	}
+	if len(models) == 0 {
+		return fmt.Errorf("no model found in %s", dir)
+	}
+	source, err := render(pkgName, models)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, output), source, 0644)
+}
+
+func receiverName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// columns 按gorm的解析规则获取结构体字段对应的数据库字段
+func (g *generator) columns(structType *ast.StructType, fieldPrefix, columnPrefix string) []column {
+	var columns []column
+	for _, field := range structType.Fields.List {
+		var tag string
+		if field.Tag != nil {
+			tag, _ = strconv.Unquote(field.Tag.Value)
+		}
+		settings := schema.ParseTagSetting(reflect.StructTag(tag).Get("gorm"), ";")
+		if v, ok := settings["-"]; ok && (v == "-" || strings.EqualFold(v, "all")) {
+			continue
+		}
+		if len(field.Names) == 0 {
+			// 匿名嵌入结构体
+			switch t := field.Type.(type) {
+			case *ast.Ident:
+				if embedded, ok := g.structs[t.Name]; ok {
+					columns = append(columns, g.columns(embedded, fieldPrefix, columnPrefix+settings["EMBEDDEDPREFIX"])...)
+				}
+			case *ast.SelectorExpr:
+				if pkg, ok := t.X.(*ast.Ident); ok && pkg.Name == "gorm" && t.Sel.Name == "Model" {
+					for _, name := range []string{"ID", "CreatedAt", "UpdatedAt", "DeletedAt"} {
+						columns = append(columns, column{field: fieldPrefix + name, name: columnPrefix + g.naming.ColumnName("", name)})
+					}
+				}
+			}
+			continue
+		}
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+			if _, ok := settings["EMBEDDED"]; ok {
+				if ident, ok := field.Type.(*ast.Ident); ok && g.structs[ident.Name] != nil {
+					columns = append(columns, g.columns(g.structs[ident.Name], fieldPrefix+name.Name, columnPrefix+settings["EMBEDDEDPREFIX"])...)
+				}
+				continue
+			}
+			if g.association(field.Type) {
+				continue
+			}
+			columnName := settings["COLUMN"]
+			if columnName == "" {
+				columnName = g.naming.ColumnName("", name.Name)
+			}
+			columns = append(columns, column{field: fieldPrefix + name.Name, name: columnPrefix + columnName})
+		}
+	}
+	return columns
+}
+
+// association 关联关系字段及无法映射为单个数据库字段的类型
+func (g *generator) association(expr ast.Expr) bool {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return g.association(t.X)
+	case *ast.Ident:
+		return g.models[t.Name]
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && t.Len == nil && ident.Name == "byte" {
+			return false
+		}
+		return t.Len == nil
+	case *ast.MapType, *ast.FuncType, *ast.ChanType, *ast.InterfaceType:
+		return true
+	}
+	return false
+}
+
+func render(pkgName string, models []model) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gormstarter-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkgName)
+	fmt.Fprintf(&buf, "import %q\n", importPath)
+	for _, m := range models {
+		fmt.Fprintf(&buf, "\n// %sColumns %s 的数据库字段\n", m.name, m.name)
+		fmt.Fprintf(&buf, "var %sColumns = struct {\n", m.name)
+		for _, c := range m.columns {
+			fmt.Fprintf(&buf, "\t%s gormstarter.Column[%s]\n", c.field, m.name)
+		}
+		buf.WriteString("}{\n")
+		for _, c := range m.columns {
+			fmt.Fprintf(&buf, "\t%s: %q,\n", c.field, c.name)
+		}
+		buf.WriteString("}\n")
+	}
+	return format.Source(buf.Bytes())
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "更新golden文件")
+
+func TestGenerate(t *testing.T) {
+	source, err := os.ReadFile(filepath.Join("testdata", "model", "model.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err = os.WriteFile(filepath.Join(dir, "model.go"), source, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = run(dir, "columns_gen.go"); err != nil {
+		t.Fatal(err)
+	}
+	generated, err := os.ReadFile(filepath.Join(dir, "columns_gen.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "model", "columns_gen.golden")
+	if *update {
+		if err = os.WriteFile(golden, generated, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(generated, expected) {
+		t.Fatalf("generated source mismatch golden file\n%s", generated)
+	}
+	// 重复执行时忽略已生成的文件
+	if err = run(dir, "columns_gen.go"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateWithoutModel(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "model.go"), []byte("package model\n\ntype Plain struct {\n\tID int64\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(dir, "columns_gen.go"); err == nil {
+		t.Fatal("expect no model error")
+	}
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Audit struct {
+	CreatedBy string
+	UpdatedBy string `gorm:"column:modifier"`
+}
+
+type Address struct {
+	City   string
+	Street string
+}
+
+type User struct {
+	gorm.Model
+	Audit
+	Name     string `gorm:"column:user_name"`
+	Email    string
+	Password string `gorm:"-"`
+	Secret   string `gorm:"-:all"`
+	Token    string `gorm:"->"`
+	Avatar   []byte
+	Tags     []string
+	Extra    map[string]any
+	Home     Address `gorm:"embedded;embeddedPrefix:home_"`
+	Orders   []*Order
+	LastSeen *time.Time
+	internal string
+}
+
+func (User) TableName() string {
+	return "user"
+}
+
+type Order struct {
+	ID     uint64 `gorm:"primaryKey"`
+	UserID uint
+	User   *User
+	Amount int64
+}
+
+func (*Order) TableName() string {
+	return "order"
+}
@@ -0,0 +1,433 @@
+package gormstarter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	AuditInsert AuditOperation = "insert"
+	AuditUpdate AuditOperation = "update"
+	AuditDelete AuditOperation = "delete"
+
+	// DefaultAuditTable 默认的变更记录表
+	DefaultAuditTable = "audit_log"
+
+	auditKey = "gormstarter:audit"
+)
+
+// AuditOperation 数据变更类型
+type AuditOperation string
+
+// IBaseModelWithAudit 模型实现此接口并返回true时 通过 BaseMapper 新增/更新/删除数据将在同一事务中记录变更历史
+type IBaseModelWithAudit interface {
+	TableName() string
+	AuditEnabled() bool
+}
+
+// AuditRecord 单行数据的变更记录
+type AuditRecord struct {
+	ID         uint64         `gorm:"primaryKey" json:"id"`
+	Table      string         `gorm:"column:table_name" json:"table"`
+	PrimaryKey string         `json:"primaryKey"` // 主键值JSON 联合主键时为数组
+	Operation  AuditOperation `json:"operation"`
+	Before     string         `gorm:"column:before_data" json:"before"` // 变更前的数据JSON 更新时仅包含变更的字段 新增时为空
+	After      string         `gorm:"column:after_data" json:"after"`   // 变更后的数据JSON 更新时仅包含变更的字段 删除时为空
+	Operator   string         `json:"operator"`                         // 操作人 由 GormConfig.OperatorExtractor 获取
+	CreatedAt  time.Time      `json:"createdAt"`
+}
+
+// AuditSink 变更记录的输出 tx 为数据变更所在的事务 返回错误时数据变更将被回滚
+type AuditSink interface {
+	Write(tx *gorm.DB, records []*AuditRecord) error
+}
+
+// TableAuditSink 将变更记录写入数据表
+type TableAuditSink struct {
+	Table string // 表名 默认为 DefaultAuditTable
+}
+
+func (s TableAuditSink) Write(tx *gorm.DB, records []*AuditRecord) error {
+	table := s.Table
+	if table == "" {
+		table = DefaultAuditTable
+	}
+	return tx.Table(table).Create(&records).Error
+}
+
+// 数据源对应的变更记录输出 key: 数据源名称
+var auditSinks = make(map[string]AuditSink)
+
+// auditor 单次写操作的变更记录上下文
+type auditor struct {
+	schema    *schema.Schema
+	table     string
+	operator  string
+	sink      AuditSink
+	operation AuditOperation // 不为空时覆盖回调对应的变更类型 软删除时为 AuditDelete
+	before    []map[string]any
+	conflict  []string // 新增语句包含冲突处理时的冲突判断字段
+	doNothing bool     // 冲突时不做任何处理
+}
+
+// audit 模型启用变更记录时为写操作附加变更记录上下文 operation 指定时覆盖实际执行的变更类型
+func (b BaseMapper[T]) audit(db *gorm.DB, operation ...AuditOperation) *gorm.DB {
+	model, ok := any(b.model).(IBaseModelWithAudit)
+	if !ok || !model.AuditEnabled() {
+		return db
+	}
+	sch, err := b.schema()
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+	a := &auditor{schema: sch, table: b.model.TableName(), sink: auditSinks[b.DataSource()]}
+	if a.sink == nil {
+		a.sink = TableAuditSink{}
+	}
+	if extractor := operatorExtractors[b.DataSource()]; extractor != nil {
+		if operator := extractor(b.context()); operator != nil {
+			a.operator = fmt.Sprint(operator)
+		}
+	}
+	if len(operation) > 0 {
+		a.operation = operation[0]
+	}
+	return db.Set(auditKey, a)
+}
+
+// registerAuditCallbacks 注册变更记录回调 仅处理通过 BaseMapper 附加了变更记录上下文的语句 与数据变更处于gorm默认开启的同一事务中
+func registerAuditCallbacks(db *gorm.DB) error {
+	create := db.Callback().Create()
+	if err := create.Before("gorm:create").After("gorm:before_create").Register("gormstarter:audit_before_create", auditBeforeCreate); err != nil {
+		return err
+	}
+	if err := create.After("gorm:create").Before("gorm:after_create").Register("gormstarter:audit_create", auditCreate); err != nil {
+		return err
+	}
+	update := db.Callback().Update()
+	if err := update.Before("gorm:update").After("gorm:before_update").Register("gormstarter:audit_before_update", auditBefore); err != nil {
+		return err
+	}
+	if err := update.After("gorm:update").Before("gorm:after_update").Register("gormstarter:audit_update", auditUpdate); err != nil {
+		return err
+	}
+	del := db.Callback().Delete()
+	if err := del.Before("gorm:delete").After("gorm:before_delete").Register("gormstarter:audit_before_delete", auditBefore); err != nil {
+		return err
+	}
+	return del.After("gorm:delete").Before("gorm:after_delete").Register("gormstarter:audit_delete", auditDelete)
+}
+
+func auditorOf(db *gorm.DB) *auditor {
+	if db.Error != nil || db.DryRun {
+		return nil
+	}
+	if v, ok := db.Get(auditKey); ok {
+		return v.(*auditor)
+	}
+	return nil
+}
+
+// auditBeforeCreate 新增语句包含冲突处理时 按冲突判断字段记录已存在的数据
+func auditBeforeCreate(db *gorm.DB) {
+	a := auditorOf(db)
+	if a == nil {
+		return
+	}
+	a.conflict, a.before = nil, nil
+	c, ok := db.Statement.Clauses["ON CONFLICT"]
+	if !ok {
+		return
+	}
+	onConflict, ok := c.Expression.(clause.OnConflict)
+	if !ok {
+		return
+	}
+	for _, column := range onConflict.Columns {
+		a.conflict = append(a.conflict, column.Name)
+	}
+	if len(a.conflict) == 0 {
+		a.conflict = a.primaryColumns()
+	}
+	a.doNothing = onConflict.DoNothing
+	var rows []map[string]any
+	for _, row := range a.rows(db, db.Statement.ReflectValue) {
+		if a.hasValues(row, a.conflict) {
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	tx := db.Session(&gorm.Session{NewDB: true}).Unscoped().Table(a.table).Where(a.columnsCond(rows, a.conflict))
+	if len(onConflict.Where.Exprs) > 0 {
+		tx = tx.Clauses(onConflict.Where)
+	}
+	a.before = a.find(db, tx)
+}
+
+// auditCreate 记录新增的数据 冲突时不做处理的数据不记录 冲突时更新的数据记录为更新
+func auditCreate(db *gorm.DB) {
+	a := auditorOf(db)
+	if a == nil || db.RowsAffected == 0 {
+		return
+	}
+	existing := make(map[string]map[string]any, len(a.before))
+	for _, row := range a.before {
+		existing[a.columnsKey(row, a.conflict)] = row
+	}
+	var records []*AuditRecord
+	var updated []map[string]any
+	for _, row := range a.rows(db, db.Statement.ReflectValue) {
+		if before, ok := existing[a.columnsKey(row, a.conflict)]; ok {
+			if !a.doNothing {
+				updated = append(updated, before)
+			}
+			continue
+		}
+		records = append(records, a.record(db, AuditInsert, row, nil, row))
+	}
+	if len(updated) > 0 {
+		records = append(records, a.changes(db, updated)...)
+	}
+	a.write(db, records)
+}
+
+// auditBefore 记录更新/删除前的数据 实体中包含主键时总是附加主键条件 与gorm实际执行的条件一致
+func auditBefore(db *gorm.DB) {
+	a := auditorOf(db)
+	if a == nil {
+		return
+	}
+	var keyCond clause.Expression
+	if reflect.Indirect(db.Statement.ReflectValue).Kind() == reflect.Struct {
+		if rows := a.rows(db, db.Statement.ReflectValue); len(rows) == 1 && a.hasValues(rows[0], a.primaryColumns()) {
+			keyCond = a.primaryKeyCond(rows)
+		}
+	}
+	// 语句条件中已包含软删除条件 查询时不再重复附加
+	tx := db.Session(&gorm.Session{NewDB: true}).Unscoped().Table(a.table)
+	where, ok := db.Statement.Clauses["WHERE"].Expression.(clause.Where)
+	switch {
+	case ok && len(where.Exprs) > 0 && keyCond != nil:
+		tx = tx.Clauses(clause.Where{Exprs: []clause.Expression{clause.And(where.Exprs...), keyCond}})
+	case ok && len(where.Exprs) > 0:
+		tx = tx.Clauses(where)
+	case keyCond != nil:
+		tx = tx.Where(keyCond)
+	default:
+		return
+	}
+	a.before = a.find(db, tx)
+}
+
+func auditUpdate(db *gorm.DB) {
+	a := auditorOf(db)
+	if a == nil || len(a.before) == 0 {
+		return
+	}
+	if a.operation == AuditDelete {
+		auditDelete(db)
+		return
+	}
+	a.write(db, a.changes(db, a.before))
+}
+
+func auditDelete(db *gorm.DB) {
+	a := auditorOf(db)
+	if a == nil || db.RowsAffected == 0 {
+		return
+	}
+	var records []*AuditRecord
+	for _, row := range a.before {
+		records = append(records, a.record(db, AuditDelete, row, row, nil))
+	}
+	a.write(db, records)
+}
+
+// rows 将实体/实体切片/map转换为 数据库字段:值 形式
+func (a *auditor) rows(db *gorm.DB, rv reflect.Value) []map[string]any {
+	rv = reflect.Indirect(rv)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		rows := make([]map[string]any, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			rows = append(rows, a.rows(db, rv.Index(i))...)
+		}
+		return rows
+	case reflect.Map:
+		row := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if field := a.schema.LookUpField(key); field != nil {
+				key = field.DBName
+			}
+			row[key] = iter.Value().Interface()
+		}
+		// 通过map新增时 gorm 将数据库返回的自增主键回填到 @id
+		if id, ok := row["@id"]; ok {
+			if field := a.schema.PrioritizedPrimaryField; field != nil && row[field.DBName] == nil {
+				row[field.DBName] = id
+			}
+			delete(row, "@id")
+		}
+		return []map[string]any{row}
+	case reflect.Struct:
+		if rv.Type() != a.schema.ModelType {
+			return nil
+		}
+		row := make(map[string]any, len(a.schema.DBNames))
+		for _, field := range a.schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			row[field.DBName], _ = field.ValueOf(db.Statement.Context, rv)
+		}
+		return []map[string]any{row}
+	}
+	return nil
+}
+
+// find 在当前事务中查询数据
+func (a *auditor) find(db *gorm.DB, tx *gorm.DB) []map[string]any {
+	records := reflect.New(reflect.SliceOf(reflect.PointerTo(a.schema.ModelType)))
+	if err := tx.Find(records.Interface()).Error; err != nil {
+		_ = db.AddError(err)
+		return nil
+	}
+	return a.rows(db, records)
+}
+
+// changes 查询更新后的数据并与更新前的数据对比 仅记录发生变更的数据
+func (a *auditor) changes(db *gorm.DB, before []map[string]any) []*AuditRecord {
+	after := make(map[string]map[string]any)
+	for _, row := range a.find(db, db.Session(&gorm.Session{NewDB: true}).Table(a.table).Unscoped().Where(a.primaryKeyCond(before))) {
+		after[a.primaryKey(row)] = row
+	}
+	var records []*AuditRecord
+	for _, row := range before {
+		changedBefore, changedAfter := diff(row, after[a.primaryKey(row)])
+		if len(changedAfter) == 0 {
+			continue
+		}
+		records = append(records, a.record(db, AuditUpdate, row, changedBefore, changedAfter))
+	}
+	return records
+}
+
+func (a *auditor) primaryColumns() []string {
+	columns := make([]string, len(a.schema.PrimaryFields))
+	for i, field := range a.schema.PrimaryFields {
+		columns[i] = field.DBName
+	}
+	return columns
+}
+
+// hasValues 指定字段均不为零值
+func (a *auditor) hasValues(row map[string]any, columns []string) bool {
+	if len(columns) == 0 {
+		return false
+	}
+	for _, column := range columns {
+		if value := row[column]; value == nil || reflect.ValueOf(value).IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *auditor) primaryKeyCond(rows []map[string]any) clause.Expression {
+	return a.columnsCond(rows, a.primaryColumns())
+}
+
+// columnsCond 按指定字段的值匹配数据
+func (a *auditor) columnsCond(rows []map[string]any, names []string) clause.Expression {
+	if len(names) == 1 {
+		values := make([]any, len(rows))
+		for i, row := range rows {
+			values[i] = row[names[0]]
+		}
+		return clause.IN{Column: clause.Column{Name: names[0]}, Values: values}
+	}
+	columns := make([]clause.Column, len(names))
+	for i, name := range names {
+		columns[i] = clause.Column{Name: name}
+	}
+	values := make([]any, len(rows))
+	for i, row := range rows {
+		keyValues := make([]any, len(names))
+		for j, name := range names {
+			keyValues[j] = row[name]
+		}
+		values[i] = keyValues
+	}
+	return clause.IN{Column: columns, Values: values}
+}
+
+func (a *auditor) primaryKey(row map[string]any) string {
+	return a.columnsKey(row, a.primaryColumns())
+}
+
+// columnsKey 指定字段的值JSON 单个字段时为该字段的值
+func (a *auditor) columnsKey(row map[string]any, columns []string) string {
+	values := make([]any, len(columns))
+	for i, column := range columns {
+		values[i] = row[column]
+	}
+	var data []byte
+	if len(values) == 1 {
+		data, _ = json.Marshal(values[0])
+	} else {
+		data, _ = json.Marshal(values)
+	}
+	return string(data)
+}
+
+func (a *auditor) record(db *gorm.DB, operation AuditOperation, row, before, after map[string]any) *AuditRecord {
+	record := &AuditRecord{
+		Table:      a.table,
+		PrimaryKey: a.primaryKey(row),
+		Operation:  operation,
+		Operator:   a.operator,
+		CreatedAt:  db.NowFunc(),
+	}
+	if before != nil {
+		data, err := json.Marshal(before)
+		_ = db.AddError(err)
+		record.Before = string(data)
+	}
+	if after != nil {
+		data, err := json.Marshal(after)
+		_ = db.AddError(err)
+		record.After = string(data)
+	}
+	return record
+}
+
+// write 输出变更记录 失败时数据变更将被回滚
+func (a *auditor) write(db *gorm.DB, records []*AuditRecord) {
+	if db.Error != nil || len(records) == 0 {
+		return
+	}
+	_ = db.AddError(a.sink.Write(db.Session(&gorm.Session{NewDB: true}), records))
+}
+
+// diff 获取变更的字段
+func diff(before, after map[string]any) (map[string]any, map[string]any) {
+	changedBefore, changedAfter := make(map[string]any), make(map[string]any)
+	for column, value := range after {
+		if !reflect.DeepEqual(before[column], value) {
+			changedBefore[column] = before[column]
+			changedAfter[column] = value
+		}
+	}
+	return changedBefore, changedAfter
+}
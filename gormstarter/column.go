@@ -0,0 +1,119 @@
+package gormstarter
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Column 模型T的数据库字段描述 通常由 cmd/gormstarter-gen 根据模型结构体及gorm标签生成
+// 字符串常量可以直接作为 Column 使用
+type Column[T IBaseModel] string
+
+// String 数据库字段名
+func (c Column[T]) String() string {
+	return string(c)
+}
+
+// Asc 升序排序语句 用于 orderBy 参数
+func (c Column[T]) Asc() string {
+	return string(c) + " ASC"
+}
+
+// Desc 降序排序语句 用于 orderBy 参数
+func (c Column[T]) Desc() string {
+	return string(c) + " DESC"
+}
+
+// Columns 将字段名转换为字段描述 用于传入 []string 类型的字段变量
+//
+//	mapper.SelectByCond(&cond, "", &result, gormstarter.Columns[model.Teacher](names...)...)
+func Columns[T IBaseModel](names ...string) []Column[T] {
+	columns := make([]Column[T], len(names))
+	for i, name := range names {
+		columns[i] = Column[T](name)
+	}
+	return columns
+}
+
+// OrderBy 将多个排序语句合并为 orderBy 参数
+//
+//	gormstarter.OrderBy(model.TeacherColumns.Age.Desc(), model.TeacherColumns.ID.Asc())
+func OrderBy(orders ...string) string {
+	return strings.Join(orders, ", ")
+}
+
+// columnNames 校验字段并转换为数据库字段名 字段不存在时返回 ErrUnknownColumn
+func (b BaseMapper[T]) columnNames(columns []Column[T]) ([]string, error) {
+	if len(columns) == 0 {
+		return nil, nil
+	}
+	sch, err := b.schema()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		if names[i], err = lookupColumn(sch, string(column)); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// selectColumns 限定查询的字段 未指定时查询全部字段
+func (b BaseMapper[T]) selectColumns(columns []Column[T]) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		names, err := b.columnNames(columns)
+		if err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+		if len(names) == 0 {
+			return tx
+		}
+		return tx.Select(names)
+	}
+}
+
+// order 校验并添加排序语句 orderBy 由逗号分隔的 字段 [ASC|DESC] 组成 更复杂的排序使用 *ByGorm 方法
+func (b BaseMapper[T]) order(orderBy string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if strings.TrimSpace(orderBy) == "" {
+			return tx
+		}
+		sch, err := b.schema()
+		if err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+		columns := make([]clause.OrderByColumn, 0, strings.Count(orderBy, ",")+1)
+		for _, term := range strings.Split(orderBy, ",") {
+			fields := strings.Fields(term)
+			if len(fields) == 0 || len(fields) > 2 {
+				_ = tx.AddError(errors.New("invalid orderBy: " + orderBy))
+				return tx
+			}
+			desc := false
+			if len(fields) == 2 {
+				switch strings.ToUpper(fields[1]) {
+				case "ASC":
+				case "DESC":
+					desc = true
+				default:
+					_ = tx.AddError(errors.New("invalid orderBy: " + orderBy))
+					return tx
+				}
+			}
+			name, err := lookupColumn(sch, fields[0])
+			if err != nil {
+				_ = tx.AddError(err)
+				return tx
+			}
+			columns = append(columns, clause.OrderByColumn{Column: clause.Column{Name: name}, Desc: desc})
+		}
+		return tx.Order(clause.OrderBy{Columns: columns})
+	}
+}
@@ -0,0 +1,206 @@
+package gormstarter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidCursor 游标无法解析或与当前查询的排序字段不匹配
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorPage 游标分页结果
+type CursorPage[T IBaseModel] struct {
+	Records    []*T
+	NextCursor string // 下一页游标 没有下一页时为空
+	PrevCursor string // 上一页游标 没有上一页时为空
+	Total      int64  // 数据总数 仅在指定 withTotal 时查询 否则为 -1
+}
+
+// cursor 游标内容 记录排序字段及当前页边界行的字段值
+type cursor struct {
+	Columns  []string          `json:"c"`
+	Values   []json.RawMessage `json:"v"`
+	Backward bool              `json:"b,omitempty"`
+}
+
+type cursorKey struct {
+	field *schema.Field
+	desc  bool
+}
+
+// FindPageByCursor 通过查询条件构造器进行游标分页 (keyset pagination) 避免深分页的OFFSET扫描
+// 排序字段由 query 的 OrderByAsc/OrderByDesc 指定 并自动追加主键作为唯一排序依据 排序字段不应包含NULL值
+// cursor 为上一次返回的 NextCursor 或 PrevCursor 首页传入空字符串 withTotal 为true时额外查询数据总数
+func (b BaseMapper[T]) FindPageByCursor(query *Query[T], cursor string, size int, withTotal ...bool) (*CursorPage[T], error) {
+	if size <= 0 {
+		return nil, errors.New("size <= 0")
+	}
+	sch, err := b.schema()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := cursorKeys(query, sch)
+	if err != nil {
+		return nil, err
+	}
+	page := &CursorPage[T]{Total: -1}
+	if len(withTotal) > 0 && withTotal[0] {
+		if page.Total, err = b.CountByQuery(query); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := b.queryDB(query, false)
+	if err != nil {
+		return nil, err
+	}
+	var backward bool
+	if cursor != "" {
+		c, values, err := decodeCursor(cursor, keys)
+		if err != nil {
+			return nil, err
+		}
+		backward = c.Backward
+		db = db.Where(keysetCond(keys, values, backward))
+	}
+	for _, key := range keys {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: key.field.DBName}, Desc: key.desc != backward})
+	}
+	columns, err := query.selectColumns(sch)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) > 0 {
+		for _, key := range keys {
+			if !slices.Contains(columns, key.field.DBName) {
+				columns = append(columns, key.field.DBName)
+			}
+		}
+		db = db.Select(columns)
+	}
+
+	records := make([]*T, 0, size+1)
+	if _, err = checkResult(db.Limit(size + 1).Scan(&records)); err != nil {
+		return nil, err
+	}
+	more := len(records) > size
+	if more {
+		records = records[:size]
+	}
+	if backward {
+		slices.Reverse(records)
+	}
+	page.Records = records
+	if len(records) == 0 {
+		return page, nil
+	}
+	// 向后翻页时 存在更多数据表示存在下一页 从非首页翻页时必然存在上一页 向前翻页时反之
+	hasNext, hasPrev := more, cursor != ""
+	if backward {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		if page.NextCursor, err = b.encodeCursor(keys, records[len(records)-1], false); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		if page.PrevCursor, err = b.encodeCursor(keys, records[0], true); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// cursorKeys 获取游标分页的排序字段 并追加主键保证排序唯一
+func cursorKeys[T IBaseModel](query *Query[T], sch *schema.Schema) ([]cursorKey, error) {
+	orders, err := query.orderBy(sch)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]cursorKey, 0, len(orders)+len(sch.PrimaryFields))
+	for _, order := range orders {
+		keys = append(keys, cursorKey{field: sch.LookUpField(order.Column.Name), desc: order.Desc})
+	}
+	if len(sch.PrimaryFields) == 0 {
+		return nil, ErrMissingPrimaryKey
+	}
+	var desc bool
+	if len(keys) > 0 {
+		desc = keys[len(keys)-1].desc
+	}
+	for _, field := range sch.PrimaryFields {
+		if !slices.ContainsFunc(keys, func(key cursorKey) bool { return key.field == field }) {
+			keys = append(keys, cursorKey{field: field, desc: desc})
+		}
+	}
+	return keys, nil
+}
+
+// keysetCond 构建游标条件 (a > ?) OR (a = ? AND b > ?) OR ...
+func keysetCond(keys []cursorKey, values []any, backward bool) clause.Expression {
+	ors := make([]clause.Expression, len(keys))
+	for i, key := range keys {
+		ands := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, clause.Eq{Column: clause.Column{Name: keys[j].field.DBName}, Value: values[j]})
+		}
+		column := clause.Column{Name: key.field.DBName}
+		if key.desc != backward {
+			ands = append(ands, clause.Lt{Column: column, Value: values[i]})
+		} else {
+			ands = append(ands, clause.Gt{Column: column, Value: values[i]})
+		}
+		ors[i] = clause.And(ands...)
+	}
+	return clause.Or(ors...)
+}
+
+func (b BaseMapper[T]) encodeCursor(keys []cursorKey, record *T, backward bool) (string, error) {
+	c := cursor{Backward: backward}
+	rv := reflect.ValueOf(record).Elem()
+	for _, key := range keys {
+		value, _ := key.field.ValueOf(b.context(), rv)
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		c.Columns = append(c.Columns, key.field.DBName)
+		c.Values = append(c.Values, raw)
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor 解析游标 字段值按模型字段类型还原
+func decodeCursor(value string, keys []cursorKey) (*cursor, []any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, nil, ErrInvalidCursor
+	}
+	c := new(cursor)
+	if err = json.Unmarshal(data, c); err != nil || len(c.Columns) != len(keys) || len(c.Values) != len(keys) {
+		return nil, nil, ErrInvalidCursor
+	}
+	values := make([]any, len(keys))
+	for i, key := range keys {
+		if c.Columns[i] != key.field.DBName {
+			return nil, nil, ErrInvalidCursor
+		}
+		ptr := reflect.New(key.field.FieldType)
+		if err = json.Unmarshal(c.Values[i], ptr.Interface()); err != nil {
+			return nil, nil, ErrInvalidCursor
+		}
+		values[i] = ptr.Elem().Interface()
+	}
+	return c, values, nil
+}
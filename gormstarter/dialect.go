@@ -1,58 +1,337 @@
 package gormstarter
 
 import (
+	"crypto/tls"
 	"errors"
-	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/acexy/golang-toolkit/logger"
 	"github.com/acexy/golang-toolkit/util/str"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// DialectOpener 通过数据源配置创建对应数据库的gorm方言
+type DialectOpener func(config *GormConfig) (gorm.Dialector, error)
+
+var dialectMutex sync.RWMutex
+var dialects = make(map[DBType]DialectOpener)
+
+func init() {
+	RegisterDialect(DBTypeMySQL, openMysqlDialector)
+	RegisterDialect(DBTypePostgres, openPostgresDialector)
+	RegisterDialect(DBTypeSQLite, openSQLiteDialector)
+}
+
+// RegisterDialect 注册数据库方言 用于接入内置类型以外的数据库 相同类型重复注册将覆盖之前的方言
+// 需要在GormStarter启动之前完成注册
+func RegisterDialect(dbType DBType, opener DialectOpener) {
+	dialectMutex.Lock()
+	defer dialectMutex.Unlock()
+	dialects[dbType] = opener
+}
+
 func openDB(config *GormConfig, gormConfig *gorm.Config) (*gorm.DB, error) {
-	switch config.DBType {
-	case DBTypeMySQL:
-		return openMysqlDB(config, gormConfig)
-	case DBTypePostgres:
-		return openPostgresDB(config, gormConfig)
+	dialector, err := openDialector(config)
+	if err != nil {
+		return nil, err
+	}
+	return gorm.Open(dialector, gormConfig)
+}
+
+func openDialector(config *GormConfig) (gorm.Dialector, error) {
+	dialectMutex.RLock()
+	opener, ok := dialects[config.DBType]
+	dialectMutex.RUnlock()
+	if !ok {
+		return nil, errors.New("not supported database type " + string(config.DBType))
+	}
+	return opener(config)
+}
+
+// openMysqlDialector 创建Mysql数据库方言
+func openMysqlDialector(config *GormConfig) (gorm.Dialector, error) {
+	dsn := config.DSN
+	if dsn == "" {
+		builder := str.NewBuilder(config.Username)
+		builder.WriteString(":").WriteString(config.Password).WriteString("@tcp(").WriteString(config.Host).WriteString(":").WriteString(strconv.Itoa(int(config.Port)))
+		params, err := mysqlParams(config)
+		if err != nil {
+			return nil, err
+		}
+		builder.WriteString(")/").WriteString(config.Database).WriteString("?").WriteString(params.Encode())
+		dsn = builder.ToString()
+	} else {
+		dsn = appendMysqlParam(dsn, "charset", config.Charset)
+		dsn = appendMysqlParam(dsn, "parseTime", "True")
+	}
+	tlsConfig, err := buildTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		// 以数据源名称注册驱动的TLS配置 并通过tls参数引用
+		tlsName := "gormstarter-" + config.Name
+		if err = mysqldriver.RegisterTLSConfig(tlsName, tlsConfig); err != nil {
+			return nil, err
+		}
+		dsn = appendMysqlParam(dsn, "tls", tlsName)
+	}
+	logDSN(config, dsn)
+	return mysql.Open(dsn), nil
+}
+
+// mysqlParams 合并Mysql连接参数 MySQLUrlParam 及URL格式DSN中的参数覆盖默认参数 同名参数只保留一个
+func mysqlParams(config *GormConfig) (url.Values, error) {
+	params := url.Values{}
+	params.Set("charset", config.Charset)
+	params.Set("parseTime", "True") // support time.Time
+	if strings.HasPrefix(config.MySQLUrlParam, "&") || strings.HasPrefix(config.MySQLUrlParam, "?") {
+		extra, err := url.ParseQuery(config.MySQLUrlParam[1:])
+		if err != nil {
+			return nil, errors.New("invalid mysql url param: " + err.Error())
+		}
+		for key, values := range extra {
+			params[key] = values
+		}
+	}
+	for key, values := range config.dsnParams {
+		params[key] = values
 	}
-	return nil, errors.New("not supported database type now")
+	return params, nil
 }
 
-// OpenMysqlDB 创建Mysql数据库连接
-func openMysqlDB(config *GormConfig, gormConfig *gorm.Config) (*gorm.DB, error) {
-	builder := str.NewBuilder(config.Username)
-	builder.WriteString(":").WriteString(config.Password).WriteString("@tcp(").WriteString(config.Host).WriteString(":").WriteString(strconv.Itoa(int(config.Port)))
-	builder.WriteString(")/").WriteString(config.Database).WriteString("?charset=" + config.Charset)
-	builder.WriteString("&parseTime=True") // support time.Time
-	if config.MySQLUrlParam != "" {
-		if strings.HasPrefix(config.MySQLUrlParam, "&") || strings.HasPrefix(config.MySQLUrlParam, "?") {
-			builder.WriteString("&").WriteString(str.Substring(config.MySQLUrlParam, 1, str.CharLength(config.MySQLUrlParam)))
+// appendMysqlParam 原生DSN中不存在指定参数时追加该参数 参数名需完全一致
+func appendMysqlParam(dsn, key, value string) string {
+	// 参数位于最后一个 / 之后的 ? 之后 密码中可能包含 ? 等字符
+	query := dsn[strings.LastIndex(dsn, "/")+1:]
+	if i := strings.Index(query, "?"); i >= 0 {
+		// 存在无法解码的参数时 ParseQuery 仍会返回其余已解析的参数
+		params, _ := url.ParseQuery(query[i+1:])
+		if params.Has(key) {
+			return dsn
 		}
+		return dsn + "&" + key + "=" + value
 	}
-	return gorm.Open(mysql.Open(builder.ToString()), gormConfig)
+	return dsn + "?" + key + "=" + value
 }
 
-func openPostgresDB(config *GormConfig, gormConfig *gorm.Config) (*gorm.DB, error) {
-	sslModel := "disable"
-	if config.PostgresEnableSSl {
-		sslModel = "enable"
+// openPostgresDialector 创建Postgres数据库方言
+func openPostgresDialector(config *GormConfig) (gorm.Dialector, error) {
+	tlsConfig, err := buildTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	dsn := postgresConfigDSN(config, tlsConfig)
+	logDSN(config, dsn)
+	if tlsConfig == nil {
+		return postgres.Open(dsn), nil
 	}
+	connConfig, err := postgresConnConfig(dsn, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return postgres.New(postgres.Config{Conn: stdlib.OpenDB(*connConfig)}), nil
+}
+
+// postgresConfigDSN 通过配置构建DSN 启用加密连接时sslmode与 TLSConfig.Mode 一致
+func postgresConfigDSN(config *GormConfig, tlsConfig *tls.Config) string {
 	timeZone := "UTC"
 	if config.PostgresTimezone != "" {
 		timeZone = config.PostgresTimezone
 	}
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=%s",
-		config.Host,
-		config.Username,
-		config.Password,
-		config.Database,
-		config.Port,
-		sslModel,
-		timeZone,
-	)
-	return gorm.Open(postgres.Open(dsn), gormConfig)
+	dsn := config.DSN
+	if dsn == "" {
+		sslModel := string(TLSModeDisable)
+		if config.PostgresEnableSSl {
+			sslModel = string(TLSModeRequire)
+		}
+		params := map[string]string{
+			"host":     config.Host,
+			"user":     config.Username,
+			"password": config.Password,
+			"dbname":   config.Database,
+			"port":     strconv.Itoa(int(config.Port)),
+			"sslmode":  sslModel,
+			"TimeZone": timeZone,
+		}
+		for key := range config.dsnParams {
+			params[key] = config.dsnParams.Get(key)
+		}
+		if tlsConfig != nil {
+			// 启用加密连接时覆盖URL中的sslmode参数
+			params["sslmode"] = string(config.TLS.Mode)
+		}
+		dsn = postgresDSN(params)
+	} else if !strings.Contains(dsn, "TimeZone=") {
+		dsn += " TimeZone=" + postgresValue(timeZone)
+	}
+	return dsn
+}
+
+// postgresConnConfig 使用自定义的TLS配置替换驱动通过sslmode生成的配置 以支持PEM内容及verify-ca模式
+func postgresConnConfig(dsn string, tlsConfig *tls.Config) (*pgx.ConnConfig, error) {
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	connConfig.TLSConfig = tlsConfig
+	connConfig.Fallbacks = nil
+	return connConfig, nil
+}
+
+var postgresKeyOrder = []string{"host", "user", "password", "dbname", "port", "sslmode", "TimeZone"}
+
+// postgresDSN 构建 key=value 格式的DSN 内置参数按固定顺序排列 其余参数按名称排序 空值参数将被忽略
+func postgresDSN(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if !slices.Contains(postgresKeyOrder, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	keys = append(slices.Clone(postgresKeyOrder), keys...)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if params[key] != "" {
+			pairs = append(pairs, key+"="+postgresValue(params[key]))
+		}
+	}
+	return strings.Join(pairs, " ")
+}
+
+// postgresValue 包含空格、引号等特殊字符的值需要使用单引号包裹
+func postgresValue(value string) string {
+	if !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+const sqliteMemory = ":memory:"
+
+func isSQLiteMemory(config *GormConfig) bool {
+	if config.DSN != "" {
+		return strings.Contains(config.DSN, sqliteMemory) || strings.Contains(config.DSN, "mode=memory")
+	}
+	return config.SQLiteFile == "" || config.SQLiteFile == sqliteMemory
+}
+
+// openSQLiteDialector 创建SQLite数据库方言 内存数据库使用共享缓存模式 以便连接池内的所有连接访问同一数据库
+func openSQLiteDialector(config *GormConfig) (gorm.Dialector, error) {
+	dsn := config.DSN
+	if dsn == "" {
+		if isSQLiteMemory(config) {
+			dsn = "file:" + config.Name + "?mode=memory&cache=shared&_busy_timeout=5000"
+		} else {
+			dsn = config.SQLiteFile + "?_busy_timeout=5000"
+		}
+	}
+	logDSN(config, dsn)
+	return sqlite.Open(dsn), nil
+}
+
+// schemeDBTypes URL格式DSN的协议对应的数据库类型
+var schemeDBTypes = map[string]DBType{
+	"mysql":      DBTypeMySQL,
+	"postgres":   DBTypePostgres,
+	"postgresql": DBTypePostgres,
+}
+
+// dsnScheme 获取URL格式DSN的协议 非URL格式时返回空字符串
+func dsnScheme(dsn string) string {
+	for _, scheme := range []string{"mysql", "postgres", "postgresql"} {
+		if strings.HasPrefix(dsn, scheme+"://") {
+			return scheme
+		}
+	}
+	return ""
+}
+
+// resolveDSN 将URL格式的DSN解析到对应的配置字段 以便Charset、PostgresTimezone等配置依旧生效
+// URL中的charset及TimeZone参数将覆盖对应的配置 其余参数将作为驱动参数使用 驱动原生格式的DSN将原样使用
+func resolveDSN(config *GormConfig) error {
+	scheme := dsnScheme(config.DSN)
+	if scheme == "" {
+		return nil
+	}
+	if dbType := schemeDBTypes[scheme]; config.DBType != dbType {
+		return errors.New("dsn scheme " + scheme + " conflicts with database type " + string(config.DBType))
+	}
+	u, err := url.Parse(config.DSN)
+	if err != nil {
+		return errors.New("invalid dsn: " + maskDSN(config.DSN))
+	}
+	config.Username = u.User.Username()
+	config.Password, _ = u.User.Password()
+	config.Host = u.Hostname()
+	if u.Port() != "" {
+		port, err := strconv.ParseUint(u.Port(), 10, 16)
+		if err != nil {
+			return errors.New("invalid dsn port: " + u.Port())
+		}
+		config.Port = uint(port)
+	} else if config.Port == 0 {
+		config.Port = 5432
+		if scheme == "mysql" {
+			config.Port = 3306
+		}
+	}
+	config.Database = strings.TrimPrefix(u.Path, "/")
+	params := u.Query()
+	if v := params.Get("charset"); v != "" {
+		config.Charset = v
+		params.Del("charset")
+	}
+	for _, key := range []string{"TimeZone", "timezone"} {
+		if v := params.Get(key); v != "" {
+			config.PostgresTimezone = v
+			params.Del(key)
+		}
+	}
+	config.dsnParams = params
+	config.DSN = ""
+	return nil
+}
+
+var postgresPasswordRegexp = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// maskDSN 隐藏DSN中的密码
+func maskDSN(dsn string) string {
+	if strings.Contains(dsn, "://") {
+		if u, err := url.Parse(dsn); err == nil {
+			return u.Redacted()
+		}
+		return "******"
+	}
+	if postgresPasswordRegexp.MatchString(dsn) {
+		return postgresPasswordRegexp.ReplaceAllString(dsn, "${1}******")
+	}
+	// mysql: user:password@protocol(address)/dbname?param=value
+	if slash := strings.LastIndex(dsn, "/"); slash > 0 {
+		if at := strings.LastIndex(dsn[:slash], "@"); at > 0 {
+			if colon := strings.Index(dsn[:at], ":"); colon >= 0 {
+				return dsn[:colon+1] + "******" + dsn[at:]
+			}
+		}
+	}
+	return dsn
+}
+
+func logDSN(config *GormConfig, dsn string) {
+	logger.Logrus().Infoln("data source", config.Name, "dsn:", maskDSN(dsn))
 }
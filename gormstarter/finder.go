@@ -0,0 +1,100 @@
+package gormstarter
+
+import (
+	"gorm.io/gorm"
+)
+
+// ErrNotFound 查询单条数据时未命中 与 gorm.ErrRecordNotFound 等价
+var ErrNotFound = gorm.ErrRecordNotFound
+
+// findOne 查询单条数据 未命中时返回 ErrNotFound
+func findOne[T any](db *gorm.DB) (*T, error) {
+	result := new(T)
+	rs := db.Limit(1).Scan(result)
+	if rs.Error != nil {
+		return nil, rs.Error
+	}
+	if rs.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return result, nil
+}
+
+// findList 查询多条数据 未命中时返回空切片
+func findList[T any](db *gorm.DB) ([]*T, error) {
+	result := make([]*T, 0)
+	if err := db.Scan(&result).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FindById 通过主键查询数据 未命中时返回 ErrNotFound 联合主键时id为 CompositeKey 或携带主键值的模型结构体
+func (b BaseMapper[T]) FindById(id any) (*T, error) {
+	cond, err := b.primaryKeyCond(id)
+	if err != nil {
+		return nil, err
+	}
+	return findOne[T](b.table().Where(cond))
+}
+
+// FindByIds 通过主键查询数据 联合主键时id元素为 CompositeKey 或携带主键值的模型结构体
+func (b BaseMapper[T]) FindByIds(ids []any) ([]*T, error) {
+	cond, err := b.primaryKeyCond(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return findList[T](b.table().Where(cond))
+}
+
+// FindOneByCond 通过条件查询单条数据 未命中时返回 ErrNotFound 查询条件零值字段将被自动忽略
+// specifyColumns 指定只需要查询的数据库字段
+func (b BaseMapper[T]) FindOneByCond(condition *T, specifyColumns ...Column[T]) (*T, error) {
+	return findOne[T](b.table().Scopes(b.selectColumns(specifyColumns)).Where(condition))
+}
+
+// FindOneByMap 通过指定字段与值查询单条数据 未命中时返回 ErrNotFound
+// specifyColumns 指定只需要查询的数据库字段
+func (b BaseMapper[T]) FindOneByMap(condition map[string]any, specifyColumns ...Column[T]) (*T, error) {
+	return findOne[T](b.table().Scopes(b.selectColumns(specifyColumns)).Where(condition))
+}
+
+// FindOneByWhere 通过原始Where SQL查询单条数据 未命中时返回 ErrNotFound
+func (b BaseMapper[T]) FindOneByWhere(rawWhereSql string, args ...any) (*T, error) {
+	return findOne[T](b.table().Where(rawWhereSql, args...))
+}
+
+// FindOne 通过查询条件构造器查询单条数据 未命中时返回 ErrNotFound
+func (b BaseMapper[T]) FindOne(query *Query[T]) (*T, error) {
+	db, err := b.queryDB(query, true)
+	if err != nil {
+		return nil, err
+	}
+	return findOne[T](db)
+}
+
+// FindListByCond 通过条件查询数据 未命中时返回空切片 查询条件零值字段将被自动忽略
+// specifyColumns 指定只需要查询的数据库字段
+func (b BaseMapper[T]) FindListByCond(condition *T, orderBy string, specifyColumns ...Column[T]) ([]*T, error) {
+	return findList[T](b.table().Scopes(b.selectColumns(specifyColumns)).Where(condition).Scopes(b.order(orderBy)))
+}
+
+// FindListByMap 通过指定字段与值查询数据 未命中时返回空切片
+// specifyColumns 指定只需要查询的数据库字段
+func (b BaseMapper[T]) FindListByMap(condition map[string]any, orderBy string, specifyColumns ...Column[T]) ([]*T, error) {
+	return findList[T](b.table().Scopes(b.selectColumns(specifyColumns)).Where(condition).Scopes(b.order(orderBy)))
+}
+
+// FindListByWhere 通过原始Where SQL查询数据 未命中时返回空切片
+func (b BaseMapper[T]) FindListByWhere(rawWhereSql, orderBy string, args ...any) ([]*T, error) {
+	return findList[T](b.table().Where(rawWhereSql, args...).Scopes(b.order(orderBy)))
+}
+
+// FindList 通过查询条件构造器查询数据 未命中时返回空切片
+func (b BaseMapper[T]) FindList(query *Query[T]) ([]*T, error) {
+	db, err := b.queryDB(query, true)
+	if err != nil {
+		return nil, err
+	}
+	return findList[T](db)
+}
@@ -0,0 +1,160 @@
+package gormstarter
+
+import (
+	"errors"
+	"iter"
+	"reflect"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrStopIteration 在 Each / FindInBatches 的回调中返回该错误将提前结束遍历 且不会作为错误返回
+var ErrStopIteration = errors.New("stop iteration")
+
+// iterate 基于 Rows() 逐行读取数据 遍历期间将持有一个数据库连接
+func iterate[T any](build func() (*gorm.DB, error)) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		db, err := build()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		rows, err := db.Rows()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer func() {
+			_ = rows.Close()
+		}()
+		for rows.Next() {
+			record := new(T)
+			if err = db.ScanRows(rows, record); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(record, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// Iterate 通过查询条件构造器流式遍历数据 不会一次性加载全部结果 遍历期间将持有一个数据库连接
+//
+//	for teacher, err := range mapper.Iterate(query) {
+//		if err != nil {
+//			return err
+//		}
+//	}
+func (b BaseMapper[T]) Iterate(query *Query[T]) iter.Seq2[*T, error] {
+	return iterate[T](func() (*gorm.DB, error) {
+		return b.queryDB(query, true)
+	})
+}
+
+// IterateByCond 通过条件流式遍历数据 查询条件零值字段将被自动忽略
+// specifyColumns 指定只需要查询的数据库字段
+func (b BaseMapper[T]) IterateByCond(condition *T, orderBy string, specifyColumns ...Column[T]) iter.Seq2[*T, error] {
+	return iterate[T](func() (*gorm.DB, error) {
+		return b.table().Scopes(b.selectColumns(specifyColumns)).Where(condition).Scopes(b.order(orderBy)), nil
+	})
+}
+
+// IterateByWhere 通过原始Where SQL流式遍历数据 只需要输入SQL语句和参数 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
+func (b BaseMapper[T]) IterateByWhere(rawWhereSql, orderBy string, args ...any) iter.Seq2[*T, error] {
+	return iterate[T](func() (*gorm.DB, error) {
+		return b.table().Where(rawWhereSql, args...).Scopes(b.order(orderBy)), nil
+	})
+}
+
+// Each 通过查询条件构造器流式遍历数据并逐条回调 回调返回 ErrStopIteration 时提前结束遍历
+func (b BaseMapper[T]) Each(query *Query[T], fn func(record *T) error) error {
+	for record, err := range b.Iterate(query) {
+		if err != nil {
+			return err
+		}
+		if err = fn(record); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// FindInBatches 通过查询条件构造器按主键顺序分批查询数据并回调 每批最多 batchSize 条 回调返回 ErrStopIteration 时提前结束
+// 分批基于主键条件 (keyset) 而非OFFSET 查询条件中的排序将被忽略 返回已处理的数据条数
+func (b BaseMapper[T]) FindInBatches(query *Query[T], batchSize int, fn func(batch []*T) error) (int64, error) {
+	if batchSize <= 0 {
+		return 0, errors.New("batchSize <= 0")
+	}
+	sch, err := b.schema()
+	if err != nil {
+		return 0, err
+	}
+	if len(sch.PrimaryFields) == 0 {
+		return 0, ErrMissingPrimaryKey
+	}
+	keys := make([]cursorKey, len(sch.PrimaryFields))
+	for i, field := range sch.PrimaryFields {
+		keys[i] = cursorKey{field: field}
+	}
+	columns, err := query.selectColumns(sch)
+	if err != nil {
+		return 0, err
+	}
+	if len(columns) > 0 {
+		for _, key := range keys {
+			if !slices.Contains(columns, key.field.DBName) {
+				columns = append(columns, key.field.DBName)
+			}
+		}
+	}
+
+	var total int64
+	var last []any
+	for {
+		db, err := b.queryDB(query, false)
+		if err != nil {
+			return total, err
+		}
+		if last != nil {
+			db = db.Where(keysetCond(keys, last, false))
+		}
+		for _, key := range keys {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: key.field.DBName}})
+		}
+		if len(columns) > 0 {
+			db = db.Select(columns)
+		}
+		batch := make([]*T, 0, batchSize)
+		if _, err = checkResult(db.Limit(batchSize).Scan(&batch)); err != nil {
+			return total, err
+		}
+		if len(batch) == 0 {
+			return total, nil
+		}
+		total += int64(len(batch))
+		if err = fn(batch); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return total, nil
+			}
+			return total, err
+		}
+		if len(batch) < batchSize {
+			return total, nil
+		}
+		rv := reflect.ValueOf(batch[len(batch)-1]).Elem()
+		last = make([]any, len(keys))
+		for i, key := range keys {
+			last[i], _ = key.field.ValueOf(b.context(), rv)
+		}
+	}
+}
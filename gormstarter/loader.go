@@ -4,46 +4,111 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/acexy/golang-toolkit/logger"
 	"github.com/golang-acexy/starter-parent/parent"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
 )
 
-const defaultCharset = "utf8mb4"
+const (
+	defaultCharset         = "utf8mb4"
+	defaultMaxOpenConns    = 100
+	defaultMaxIdleConns    = 10
+	defaultConnMaxLifetime = time.Hour
+	defaultConnMaxIdleTime = time.Minute * 10
+)
+
+// 管理多数据源操作实例 key: 数据源名称
+var gormDBs map[string]*gorm.DB
+
+// 数据库类型与该类型最先加载的数据源名称
+var dbTypeDataSources map[DBType]string
+var defaultDataSource string
+
+// 已加载的数据源 按加载顺序排列 用于卸载默认数据源后重新选取
+var loadedDataSources []loadedDataSource
+
+type loadedDataSource struct {
+	name   string
+	dbType DBType
+}
 
-// 管理多类型数据库操作实例
-var gormDBs map[DBType]*gorm.DB
-var defaultDBType DBType
+// ErrUnknownDataSource 数据源不存在或已卸载
+var ErrUnknownDataSource = errors.New("unknown data source")
 var sqlLoggerLevel logger.Level
 
 func init() {
-	gormDBs = make(map[DBType]*gorm.DB)
+	gormDBs = make(map[string]*gorm.DB)
+	dbTypeDataSources = make(map[DBType]string)
 }
 
 type GormConfig struct {
+	// 数据源名称 不指定时默认为数据库类型名称 当同一数据库类型存在多个数据源时需要分别指定
+	Name string
+
 	Username string
 	Password string
 	Host     string
 	Port     uint
 	Database string
 
+	// 完整的数据库连接串 优先级高于 Username/Password/Host/Port/Database
+	// 支持 mysql:// postgres:// postgresql:// URL格式 (将被解析到对应的配置字段) 以及驱动原生格式
+	DSN string
+
 	Charset string // default charset : utf8mb4
-	DBType  DBType // 数据库类型 不指定时默认为 mysql
+	DBType  DBType // 数据库类型 不指定时默认为 mysql 使用URL格式的DSN时默认为URL对应的类型
 
 	TimeUTC       bool         // true: create/update UTC time; false LOCAL time
 	DryRun        bool         // create sql not exec
 	SQLoggerLevel logger.Level // 仅当不使用默认日志时，才生效 仅指定为InfoLevel	DebugLevel	TraceLevel 时才生效，默认为 DebugLevel
 
+	// 批量新增时每批的最大条数 默认不分批 用于避免超出 mysql max_allowed_packet 及 postgres 65535 参数数量限制
+	CreateBatchSize int
+
+	// 连接池配置
+	MaxOpenConns    int           // 最大打开连接数 默认 100 小于0时不限制
+	MaxIdleConns    int           // 最大空闲连接数 默认 10 小于0时不保留空闲连接
+	ConnMaxLifetime time.Duration // 连接最大复用时间 默认 1小时 小于0时不限制
+	ConnMaxIdleTime time.Duration // 连接最大空闲时间 默认 10分钟 小于0时不限制
+
 	// MYSQL 配置
 	MySQLUrlParam string // more Param such as `allowNativePasswords=false&checkConnLiveness=false`  https://github.com/go-sql-driver/mysql?tab=readme-ov-file#dsn-data-source-name
 
 	// Postgres 配置
-	PostgresTimezone  string
+	PostgresTimezone string
+	// Deprecated: 使用 TLS 配置代替 该配置等同于 TLSModeRequire
 	PostgresEnableSSl bool
 
+	// 加密连接配置 支持 mysql postgres
+	TLS *TLSConfig
+
+	// 读写分离 从库配置 配置后BaseMapper的查询操作将路由到从库 写操作及事务内的操作使用主库
+	Replicas      []ReplicaConfig
+	ReplicaPolicy ReplicaPolicy // 从库负载均衡策略 默认为 random
+
+	// SQLite 配置
+	SQLiteFile string // 数据库文件路径 为空或为 :memory: 时使用内存数据库
+
+	// 从上下文中获取当前操作人 BaseMapper 新增/更新/软删除时自动填充模型的 created_by/updated_by/deleted_by 字段 返回nil时不填充
+	OperatorExtractor func(ctx context.Context) any
+
+	// 变更记录输出 实现 IBaseModelWithAudit 的模型通过 BaseMapper 变更数据时使用 默认写入 DefaultAuditTable 表
+	AuditSink AuditSink
+
+	// 从上下文中获取当前租户 实现 IBaseModelWithTenant 的模型通过 BaseMapper 操作数据时自动限定租户 返回nil时拒绝执行
+	TenantResolver func(ctx context.Context) any
+
 	InitFunc func(instance *gorm.DB)
+
+	// URL格式DSN中的额外参数
+	dsnParams url.Values
 }
 
 type GormStarter struct {
@@ -53,6 +118,7 @@ type GormStarter struct {
 	LazyConfig  func() GormConfig
 	config      *GormConfig
 	GormSetting *parent.Setting
+	replicas    []*sql.DB
 }
 
 func (g *GormStarter) getConfig() *GormConfig {
@@ -60,16 +126,18 @@ func (g *GormStarter) getConfig() *GormConfig {
 		if g.LazyConfig != nil {
 			lazyGormConfig := g.LazyConfig()
 			g.config = &lazyGormConfig
-			g.config.DBType = lazyGormConfig.DBType
-			if g.config.DBType == "" {
-				g.config.DBType = DBTypeMySQL
-			}
 		} else {
 			g.config = &g.Config
-			if g.config.DBType == "" {
-				g.config.DBType = DBTypeMySQL
+		}
+		if g.config.DBType == "" {
+			g.config.DBType = DBTypeMySQL
+			if scheme := dsnScheme(g.config.DSN); scheme == "postgres" || scheme == "postgresql" {
+				g.config.DBType = DBTypePostgres
 			}
 		}
+		if g.config.Name == "" {
+			g.config.Name = string(g.config.DBType)
+		}
 	}
 	if g.config.SQLoggerLevel < logger.InfoLevel {
 		sqlLoggerLevel = logger.DebugLevel
@@ -84,7 +152,7 @@ func (g *GormStarter) Setting() *parent.Setting {
 		return g.GormSetting
 	}
 	config := g.getConfig()
-	return parent.NewSetting("Gorm-Starter: "+string(config.DBType), 20, true, time.Second*30, func(instance any) {
+	return parent.NewSetting("Gorm-Starter: "+config.Name, 20, true, time.Second*30, func(instance any) {
 		if config.InitFunc != nil {
 			config.InitFunc(instance.(*gorm.DB))
 		}
@@ -97,6 +165,7 @@ func (g *GormStarter) Start() (any, error) {
 	rawGormConfig := &gorm.Config{
 		DisableForeignKeyConstraintWhenMigrating: true,
 		DryRun:                                   config.DryRun,
+		CreateBatchSize:                          config.CreateBatchSize,
 	}
 	rawGormConfig.Logger = &logrusLogger{}
 	if config.TimeUTC {
@@ -104,35 +173,98 @@ func (g *GormStarter) Start() (any, error) {
 			return time.Now().UTC()
 		}
 	}
-	if config.DBType == "" {
-		config.DBType = DBTypeMySQL
-	}
 	if config.Charset == "" {
 		config.Charset = defaultCharset
 	}
-	if defaultDBType == "" {
-		defaultDBType = config.DBType
+	if err = resolveDSN(config); err != nil {
+		return nil, err
 	}
-	_, ok := gormDBs[config.DBType]
+	_, ok := gormDBs[config.Name]
 	if ok {
-		return nil, errors.New("database type " + string(config.DBType) + " already exist")
+		return nil, errors.New("data source " + config.Name + " already exist")
 	}
 	gormDB, err := openDB(config, rawGormConfig)
 	if err != nil {
 		return nil, err
 	}
+	if err = registerAuditCallbacks(gormDB); err != nil {
+		return nil, err
+	}
 	sqlDb, err := gormDB.DB()
 	if err != nil {
 		return nil, err
 	}
+	g.setPool(config.Name, sqlDb)
 	err = g.ping(sqlDb)
 	if err != nil {
 		return nil, err
 	}
-	gormDBs[config.DBType] = gormDB
+	if len(config.Replicas) > 0 {
+		g.replicas, err = useReplicas(gormDB, config)
+		if err != nil {
+			return nil, err
+		}
+		for i, replica := range g.replicas {
+			g.setPool(replicaName(config, i), replica)
+			if err = g.ping(replica); err != nil {
+				return nil, err
+			}
+		}
+	}
+	gormDBs[config.Name] = gormDB
+	loadedDataSources = append(loadedDataSources, loadedDataSource{name: config.Name, dbType: config.DBType})
+	if config.OperatorExtractor != nil {
+		operatorExtractors[config.Name] = config.OperatorExtractor
+	}
+	if config.AuditSink != nil {
+		auditSinks[config.Name] = config.AuditSink
+	}
+	if config.TenantResolver != nil {
+		tenantResolvers[config.Name] = config.TenantResolver
+	}
+	if defaultDataSource == "" {
+		defaultDataSource = config.Name
+	}
+	if _, ok = dbTypeDataSources[config.DBType]; !ok {
+		dbTypeDataSources[config.DBType] = config.Name
+	}
 	return gormDB, nil
 }
 
+// setPool 设置连接池参数
+func (g *GormStarter) setPool(name string, sqlDb *sql.DB) {
+	if sqlDb == nil {
+		return
+	}
+	config := g.getConfig()
+	maxOpenConns := poolSetting(config.MaxOpenConns, defaultMaxOpenConns)
+	maxIdleConns := poolSetting(config.MaxIdleConns, defaultMaxIdleConns)
+	connMaxLifetime := poolSetting(config.ConnMaxLifetime, defaultConnMaxLifetime)
+	connMaxIdleTime := poolSetting(config.ConnMaxIdleTime, defaultConnMaxIdleTime)
+	if config.DBType == DBTypeSQLite && isSQLiteMemory(config) {
+		// 内存数据库在最后一个连接关闭时将被销毁 需要始终保留空闲连接
+		connMaxLifetime, connMaxIdleTime = 0, 0
+		maxIdleConns = max(maxIdleConns, 1)
+	}
+	sqlDb.SetMaxOpenConns(maxOpenConns)
+	sqlDb.SetMaxIdleConns(maxIdleConns)
+	sqlDb.SetConnMaxLifetime(connMaxLifetime)
+	sqlDb.SetConnMaxIdleTime(connMaxIdleTime)
+	logger.Logrus().Infoln("data source", name, "pool settings maxOpenConns:", maxOpenConns, "maxIdleConns:", maxIdleConns,
+		"connMaxLifetime:", connMaxLifetime, "connMaxIdleTime:", connMaxIdleTime)
+}
+
+// poolSetting 零值使用默认值 负值表示不限制
+func poolSetting[V int | time.Duration](value, defaultValue V) V {
+	if value == 0 {
+		return defaultValue
+	}
+	if value < 0 {
+		return 0
+	}
+	return value
+}
+
 func (g *GormStarter) ping(sqlDb *sql.DB) error {
 	if sqlDb == nil {
 		return nil
@@ -140,32 +272,37 @@ func (g *GormStarter) ping(sqlDb *sql.DB) error {
 	return sqlDb.Ping()
 }
 
-func (g *GormStarter) closedAllConn(sqlDb *sql.DB) bool {
-	if sqlDb == nil {
-		return true
-	}
-	s := sqlDb.Stats()
-	if s.Idle == 0 && s.InUse == 0 && s.OpenConnections == 0 {
-		return true
+func (g *GormStarter) closedAllConn(sqlDbs ...*sql.DB) bool {
+	for _, sqlDb := range sqlDbs {
+		if sqlDb == nil {
+			continue
+		}
+		s := sqlDb.Stats()
+		if s.Idle != 0 || s.InUse != 0 || s.OpenConnections != 0 {
+			return false
+		}
 	}
-	return false
+	return true
 }
 
 func (g *GormStarter) Stop(maxWaitTime time.Duration) (gracefully, stopped bool, err error) {
-	dbType := g.getConfig().DBType
-	gormDB := gormDBs[dbType]
+	gormDB := gormDBs[g.getConfig().Name]
 	sqlDb, err := gormDB.DB()
 	if err != nil {
 		return false, g.ping(sqlDb) != nil, err
 	}
-	err = sqlDb.Close()
-	if err != nil {
-		return false, g.ping(sqlDb) != nil, err
+	sqlDbs := append([]*sql.DB{sqlDb}, g.replicas...)
+	for _, db := range sqlDbs {
+		err = db.Close()
+		if err != nil {
+			return false, g.ping(sqlDb) != nil, err
+		}
 	}
+	unregister(g.getConfig())
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	go func() {
 		for {
-			if g.closedAllConn(sqlDb) {
+			if g.closedAllConn(sqlDbs...) {
 				cancelFunc()
 				return
 			}
@@ -183,12 +320,90 @@ func (g *GormStarter) Stop(maxWaitTime time.Duration) (gracefully, stopped bool,
 	return
 }
 
-// RawGormDB 获取 gorm.DB原始能力，如果多数据库类型初始化后，不指定DBType默认返回最先加载的数据库类型
+// unregister 移除已关闭的数据源
+func unregister(config *GormConfig) {
+	delete(gormDBs, config.Name)
+	delete(operatorExtractors, config.Name)
+	delete(auditSinks, config.Name)
+	delete(tenantResolvers, config.Name)
+	for i, loaded := range loadedDataSources {
+		if loaded.name == config.Name {
+			loadedDataSources = append(loadedDataSources[:i], loadedDataSources[i+1:]...)
+			break
+		}
+	}
+	// 默认数据源被卸载时 指向剩余数据源中最先加载的一个
+	if dbTypeDataSources[config.DBType] == config.Name {
+		delete(dbTypeDataSources, config.DBType)
+		for _, loaded := range loadedDataSources {
+			if loaded.dbType == config.DBType {
+				dbTypeDataSources[config.DBType] = loaded.name
+				break
+			}
+		}
+	}
+	if defaultDataSource == config.Name {
+		defaultDataSource = ""
+		if len(loadedDataSources) > 0 {
+			defaultDataSource = loadedDataSources[0].name
+		}
+	}
+}
+
+// unknownDataSource 获取携带数据源不存在错误的gorm.DB 未注册任何回调 后续操作均不会执行并返回该错误
+func unknownDataSource(name string) *gorm.DB {
+	db, _ := gorm.Open(unknownDialector{})
+	_ = db.AddError(fmt.Errorf("%w %s", ErrUnknownDataSource, name))
+	return db
+}
+
+// unknownDialector 数据源不存在时使用的空方言
+type unknownDialector struct{}
+
+func (unknownDialector) Name() string {
+	return "unknown"
+}
+
+func (unknownDialector) Initialize(*gorm.DB) error {
+	return nil
+}
+
+func (d unknownDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return migrator.Migrator{Config: migrator.Config{DB: db, Dialector: d}}
+}
+
+func (unknownDialector) DataTypeOf(*schema.Field) string {
+	return ""
+}
+
+func (unknownDialector) DefaultValueOf(*schema.Field) clause.Expression {
+	return clause.Expr{SQL: "DEFAULT"}
+}
+
+func (unknownDialector) BindVarTo(writer clause.Writer, _ *gorm.Statement, _ any) {
+	_ = writer.WriteByte('?')
+}
+
+func (unknownDialector) QuoteTo(writer clause.Writer, str string) {
+	_, _ = writer.WriteString(str)
+}
+
+func (unknownDialector) Explain(sql string, _ ...any) string {
+	return sql
+}
+
+// RawGormDB 获取 gorm.DB原始能力，如果多数据库类型初始化后，不指定DBType默认返回最先加载的数据源
+// 同一数据库类型存在多个数据源时，返回该类型最先加载的数据源
 func RawGormDB(dbType ...DBType) *gorm.DB {
 	if len(dbType) == 0 {
-		return gormDBs[defaultDBType]
+		return gormDBs[defaultDataSource]
 	}
-	return gormDBs[dbType[0]]
+	return gormDBs[dbTypeDataSources[dbType[0]]]
+}
+
+// RawGormDBByName 通过数据源名称获取 gorm.DB原始能力
+func RawGormDBByName(dataSource string) *gorm.DB {
+	return gormDBs[dataSource]
 }
 
 // RawMysqlGormDB 获取 mysql 数据库类型的 gorm.DB
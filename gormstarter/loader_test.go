@@ -0,0 +1,67 @@
+package gormstarter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type unknownModel struct {
+	ID   int64
+	Name string
+}
+
+func (unknownModel) TableName() string {
+	return "unknown_model"
+}
+
+func TestUnregisterDefaultDataSource(t *testing.T) {
+	defer func(dbs map[string]*gorm.DB, types map[DBType]string, loaded []loadedDataSource, name string) {
+		gormDBs, dbTypeDataSources, loadedDataSources, defaultDataSource = dbs, types, loaded, name
+	}(gormDBs, dbTypeDataSources, loadedDataSources, defaultDataSource)
+
+	gormDBs = map[string]*gorm.DB{"m1": {}, "p1": {}, "m2": {}}
+	dbTypeDataSources = map[DBType]string{DBTypeMySQL: "m1", DBTypePostgres: "p1"}
+	loadedDataSources = []loadedDataSource{{"m1", DBTypeMySQL}, {"p1", DBTypePostgres}, {"m2", DBTypeMySQL}}
+	defaultDataSource = "m1"
+
+	unregister(&GormConfig{Name: "m1", DBType: DBTypeMySQL})
+	if defaultDataSource != "p1" {
+		t.Fatal("default data source not re-pointed", defaultDataSource)
+	}
+	if dbTypeDataSources[DBTypeMySQL] != "m2" {
+		t.Fatal("mysql data source not re-pointed", dbTypeDataSources[DBTypeMySQL])
+	}
+
+	unregister(&GormConfig{Name: "p1", DBType: DBTypePostgres})
+	if defaultDataSource != "m2" {
+		t.Fatal("default data source not re-pointed", defaultDataSource)
+	}
+	if _, ok := dbTypeDataSources[DBTypePostgres]; ok {
+		t.Fatal("postgres data source should be removed")
+	}
+
+	unregister(&GormConfig{Name: "m2", DBType: DBTypeMySQL})
+	if defaultDataSource != "" || len(dbTypeDataSources) != 0 {
+		t.Fatal("data sources should be empty", defaultDataSource, dbTypeDataSources)
+	}
+}
+
+func TestUnknownDataSource(t *testing.T) {
+	mapper := BaseMapper[unknownModel]{}.GetBaseMapperWithDataSource("missing")
+	if _, err := mapper.CountByCond(&unknownModel{Name: "a"}); !errors.Is(err, ErrUnknownDataSource) {
+		t.Fatal("expect unknown data source error", err)
+	}
+	var result unknownModel
+	if _, err := mapper.NewBaseMapperWithTx().SelectById(1, &result); !errors.Is(err, ErrUnknownDataSource) {
+		t.Fatal("expect unknown data source error", err)
+	}
+	err := TransactionWithDataSource(context.Background(), "missing", func(ctx context.Context) error {
+		return nil
+	})
+	if !errors.Is(err, ErrUnknownDataSource) {
+		t.Fatal("expect unknown data source error", err)
+	}
+}
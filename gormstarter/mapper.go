@@ -1,6 +1,7 @@
 package gormstarter
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"math"
@@ -8,19 +9,36 @@ import (
 	"github.com/acexy/golang-toolkit/util/coll"
 	"github.com/acexy/golang-toolkit/util/reflect"
 	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
 )
 
 func (b BaseMapper[T]) rawDB() *gorm.DB {
-	if b.tx != nil {
-		return b.tx
+	db := b.tx
+	if db == nil {
+		// 加入上下文中由 Transaction 开启的事务
+		db = TxFromContext(b.ctx, b.DataSource())
 	}
-	if len(gormDBs) == 1 {
-		return gormDBs[defaultDBType]
+	if db == nil {
+		var ok bool
+		if db, ok = gormDBs[b.DataSource()]; !ok {
+			return unknownDataSource(b.DataSource())
+		}
+		if b.primary {
+			db = db.Clauses(dbresolver.Write)
+		}
 	}
-	if v, flag := any(b.model).(IBaseModelWithDBType); flag {
-		return gormDBs[v.DBType()]
+	if b.ctx != nil {
+		return db.WithContext(b.ctx)
 	}
-	return gormDBs[defaultDBType]
+	return db
+}
+
+// context 获取当前Mapper所使用的上下文
+func (b BaseMapper[T]) context() context.Context {
+	if b.ctx != nil {
+		return b.ctx
+	}
+	return context.Background()
 }
 
 func checkResult(rs *gorm.DB, txCheck ...bool) (int64, error) {
@@ -41,83 +59,172 @@ func (b BaseMapper[T]) GormWithTableName() *gorm.DB {
 
 // CurrentGorm 获取当前Mapper所使用的gorm.DB 如果当前Mapper已使用指定的事务，则返回当前Mapper所使用的事务，否则获取新的gorm.DB
 func (b BaseMapper[T]) CurrentGorm() *gorm.DB {
-	if b.tx != nil {
-		return b.tx
-	}
 	return b.rawDB()
 }
 
 // GetBaseMapperWithTx 获取携带指定事务的基础Mapper
 func (b BaseMapper[T]) GetBaseMapperWithTx(tx *gorm.DB) BaseMapper[T] {
 	return BaseMapper[T]{
-		model: b.model,
-		tx:    tx,
+		model:      b.model,
+		tx:         tx,
+		dataSource: b.dataSource,
+		ctx:        b.ctx,
+		primary:    b.primary,
+		unscoped:   b.unscoped,
+		noTenant:   b.noTenant,
 	}
 }
 
-// NewBaseMapperWithTx 创建一个全新事务的基础Mapper
+// NewBaseMapperWithTx 获取事务中的基础Mapper 传播行为为 PropagationRequired
+// Mapper或上下文中已存在事务时加入该事务 此时 Commit 由事务的发起方负责 Rollback 将回滚整个事务 否则开启新事务
 func (b BaseMapper[T]) NewBaseMapperWithTx(opts ...*sql.TxOptions) BaseMapper[T] {
 	baseMapper := BaseMapper[T]{
-		model: b.model,
-	}
-	baseMapper.tx = baseMapper.rawDB().Begin(opts...)
+		model:      b.model,
+		dataSource: b.dataSource,
+		ctx:        b.ctx,
+		primary:    b.primary,
+		unscoped:   b.unscoped,
+		noTenant:   b.noTenant,
+	}
+	existing := b.tx
+	if existing == nil {
+		existing = TxFromContext(b.ctx, baseMapper.DataSource())
+	}
+	if existing != nil {
+		if tx := joinTx(baseMapper.context(), existing); tx != nil {
+			baseMapper.tx = tx
+			return baseMapper
+		}
+	}
+	db, ok := gormDBs[baseMapper.DataSource()]
+	if !ok {
+		baseMapper.tx = unknownDataSource(baseMapper.DataSource())
+		return baseMapper
+	}
+	if baseMapper.ctx != nil {
+		db = db.WithContext(baseMapper.ctx)
+	}
+	baseMapper.tx = db.Begin(opts...)
 	return baseMapper
 }
 
-// SelectById 通过主键查询数据
+// GetBaseMapperWithDataSource 获取使用指定数据源的基础Mapper 优先级高于模型自身声明的数据源
+func (b BaseMapper[T]) GetBaseMapperWithDataSource(dataSource string) BaseMapper[T] {
+	return BaseMapper[T]{
+		model:      b.model,
+		dataSource: dataSource,
+		ctx:        b.ctx,
+		primary:    b.primary,
+		unscoped:   b.unscoped,
+		noTenant:   b.noTenant,
+	}
+}
+
+// WithContext 获取携带指定上下文的基础Mapper 该Mapper执行的所有操作都将使用此上下文 用于传递取消信号、超时及链路信息
+func (b BaseMapper[T]) WithContext(ctx context.Context) BaseMapper[T] {
+	return BaseMapper[T]{
+		model:      b.model,
+		tx:         b.tx,
+		dataSource: b.dataSource,
+		ctx:        ctx,
+		primary:    b.primary,
+		unscoped:   b.unscoped,
+		noTenant:   b.noTenant,
+	}
+}
+
+// WithPrimary 获取强制使用主库的基础Mapper 配置读写分离时查询操作也将路由到主库 用于写后立即读的场景
+func (b BaseMapper[T]) WithPrimary() BaseMapper[T] {
+	return BaseMapper[T]{
+		model:      b.model,
+		tx:         b.tx,
+		dataSource: b.dataSource,
+		ctx:        b.ctx,
+		primary:    true,
+		unscoped:   b.unscoped,
+		noTenant:   b.noTenant,
+	}
+}
+
+// DataSource 获取当前Mapper所使用的数据源名称
+func (b BaseMapper[T]) DataSource() string {
+	if b.dataSource != "" {
+		return b.dataSource
+	}
+	if len(gormDBs) == 1 {
+		return defaultDataSource
+	}
+	if v, flag := any(b.model).(IBaseModelWithDataSource); flag {
+		return v.DataSource()
+	}
+	if v, flag := any(b.model).(IBaseModelWithDBType); flag {
+		return dbTypeDataSources[v.DBType()]
+	}
+	return defaultDataSource
+}
+
+// SelectById 通过主键查询数据 联合主键时id为 CompositeKey 或携带主键值的模型结构体
 func (b BaseMapper[T]) SelectById(id any, result *T) (int64, error) {
-	return checkResult(b.rawDB().Table(b.model.TableName()).Where("id = ?", id).Scan(result))
+	cond, err := b.primaryKeyCond(id)
+	if err != nil {
+		return 0, err
+	}
+	return checkResult(b.table().Where(cond).Scan(result))
 }
 
-// SelectByIds 通过主键查询数据
+// SelectByIds 通过主键查询数据 联合主键时id元素为 CompositeKey 或携带主键值的模型结构体
 func (b BaseMapper[T]) SelectByIds(id []any, result *[]*T) (int64, error) {
-	return checkResult(b.rawDB().Table(b.model.TableName()).Where("id in ?", id).Scan(result))
+	cond, err := b.primaryKeyCond(id...)
+	if err != nil {
+		return 0, err
+	}
+	return checkResult(b.table().Where(cond).Scan(result))
 }
 
 // SelectOneByCond 通过条件查询 查询条件零值字段将被自动忽略
 // specifyColumns 指定只需要查询的数据库字段
-func (b BaseMapper[T]) SelectOneByCond(condition, result *T, specifyColumns ...string) (int64, error) {
-	return checkResult(b.rawDB().Table(b.model.TableName()).Select(specifyColumns).Where(condition).Scan(result))
+func (b BaseMapper[T]) SelectOneByCond(condition, result *T, specifyColumns ...Column[T]) (int64, error) {
+	return checkResult(b.table().Scopes(b.selectColumns(specifyColumns)).Where(condition).Scan(result))
 }
 
 // SelectOneByMap 通过指定字段与值查询数据 解决查询条件零值问题
 // specifyColumns 指定只需要查询的数据库字段
-func (b BaseMapper[T]) SelectOneByMap(condition map[string]any, result *T, specifyColumns ...string) (int64, error) {
-	return checkResult(b.rawDB().Table(b.model.TableName()).Select(specifyColumns).Where(condition).Scan(result))
+func (b BaseMapper[T]) SelectOneByMap(condition map[string]any, result *T, specifyColumns ...Column[T]) (int64, error) {
+	return checkResult(b.table().Scopes(b.selectColumns(specifyColumns)).Where(condition).Scan(result))
 }
 
 // SelectOneByWhere 通过原始Where SQL查询 只需要输入SQL语句和参数 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
 func (b BaseMapper[T]) SelectOneByWhere(rawWhereSql string, result *T, args ...any) (int64, error) {
-	return checkResult(b.rawDB().Table(b.model.TableName()).Where(rawWhereSql, args...).Scan(result))
+	return checkResult(b.table().Where(rawWhereSql, args...).Scan(result))
 }
 
 // SelectOneByGorm 通过原始Gorm查询单条数据 构建Gorm查询条件
 func (b BaseMapper[T]) SelectOneByGorm(result *T, rawDb func(*gorm.DB)) (int64, error) {
-	var db = b.rawDB().Table(b.model.TableName())
+	var db = b.table()
 	rawDb(db)
 	return checkResult(db.Scan(result))
 }
 
 // SelectByCond 通过条件查询 查询条件零值字段将被自动忽略
 // specifyColumns 指定只需要查询的数据库字段
-func (b BaseMapper[T]) SelectByCond(condition *T, orderBy string, result *[]*T, specifyColumns ...string) (int64, error) {
-	return checkResult(b.rawDB().Table(b.model.TableName()).Select(specifyColumns).Where(condition).Order(orderBy).Scan(result))
+func (b BaseMapper[T]) SelectByCond(condition *T, orderBy string, result *[]*T, specifyColumns ...Column[T]) (int64, error) {
+	return checkResult(b.table().Scopes(b.selectColumns(specifyColumns)).Where(condition).Scopes(b.order(orderBy)).Scan(result))
 }
 
 // SelectByMap 通过指定字段与值查询数据 解决零值条件问题
 // specifyColumns 指定只需要查询的数据库字段
-func (b BaseMapper[T]) SelectByMap(condition map[string]any, orderBy string, result *[]*T, specifyColumns ...string) (int64, error) {
-	return checkResult(b.rawDB().Table(b.model.TableName()).Select(specifyColumns).Where(condition).Order(orderBy).Scan(result))
+func (b BaseMapper[T]) SelectByMap(condition map[string]any, orderBy string, result *[]*T, specifyColumns ...Column[T]) (int64, error) {
+	return checkResult(b.table().Scopes(b.selectColumns(specifyColumns)).Where(condition).Scopes(b.order(orderBy)).Scan(result))
 }
 
 // SelectByWhere 通过原始Where SQL查询 只需要输入SQL语句和参数 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
 func (b BaseMapper[T]) SelectByWhere(rawWhereSql, orderBy string, result *[]*T, args ...any) (int64, error) {
-	return checkResult(b.rawDB().Table(b.model.TableName()).Where(rawWhereSql, args...).Order(orderBy).Scan(result))
+	return checkResult(b.table().Where(rawWhereSql, args...).Scopes(b.order(orderBy)).Scan(result))
 }
 
 // SelectByGorm 通过原始Gorm查询数据
 func (b BaseMapper[T]) SelectByGorm(result *[]*T, rawDb func(*gorm.DB)) (int64, error) {
-	var db = b.rawDB().Table(b.model.TableName())
+	var db = b.table()
 	rawDb(db)
 	return checkResult(db.Scan(result))
 }
@@ -125,28 +232,28 @@ func (b BaseMapper[T]) SelectByGorm(result *[]*T, rawDb func(*gorm.DB)) (int64,
 // CountByCond 通过条件查询数据总数 查询条件零值字段将被自动忽略
 func (b BaseMapper[T]) CountByCond(condition *T) (int64, error) {
 	var count int64
-	_, err := checkResult(b.rawDB().Table(b.model.TableName()).Where(condition).Count(&count))
+	_, err := checkResult(b.table().Where(condition).Count(&count))
 	return count, err
 }
 
 // CountByMap 通过指定字段与值查询数据总数 解决零值条件问题
 func (b BaseMapper[T]) CountByMap(condition map[string]any) (int64, error) {
 	var count int64
-	_, err := checkResult(b.rawDB().Table(b.model.TableName()).Where(condition).Count(&count))
+	_, err := checkResult(b.table().Where(condition).Count(&count))
 	return count, err
 }
 
 // CountByWhere 通过原始SQL查询数据总数
 func (b BaseMapper[T]) CountByWhere(rawWhereSql string, args ...any) (int64, error) {
 	var count int64
-	_, err := checkResult(b.rawDB().Table(b.model.TableName()).Where(rawWhereSql, args...).Count(&count))
+	_, err := checkResult(b.table().Where(rawWhereSql, args...).Count(&count))
 	return count, err
 }
 
 // CountByGorm 通过原始Gorm查询数据总数
 func (b BaseMapper[T]) CountByGorm(raw func(*gorm.DB)) (int64, error) {
 	var count int64
-	var db = b.rawDB().Table(b.model.TableName())
+	var db = b.table()
 	raw(db)
 	_, err := checkResult(db.Count(&count))
 	return count, err
@@ -154,18 +261,18 @@ func (b BaseMapper[T]) CountByGorm(raw func(*gorm.DB)) (int64, error) {
 
 // SelectPageByCond 通过条件分页查询 零值字段将被自动忽略
 // specifyColumns 指定只需要查询的数据库字段 pageNumber 页码 1开始
-func (b BaseMapper[T]) SelectPageByCond(condition *T, orderBy string, pageNumber, pageSize int, result *[]*T, specifyColumns ...string) (total int64, err error) {
+func (b BaseMapper[T]) SelectPageByCond(condition *T, orderBy string, pageNumber, pageSize int, result *[]*T, specifyColumns ...Column[T]) (total int64, err error) {
 	if pageNumber <= 0 || pageSize <= 0 {
 		return 0, errors.New("pageNumber or pageSize <= 0")
 	}
-	_, err = checkResult(b.rawDB().Table(b.model.TableName()).Where(condition).Count(&total))
+	_, err = checkResult(b.table().Where(condition).Count(&total))
 	if err != nil {
 		return 0, err
 	}
 	if total <= 0 {
 		return 0, nil
 	}
-	_, err = checkResult(b.rawDB().Table(b.model.TableName()).Select(specifyColumns).Where(condition).Order(orderBy).Limit(pageSize).Offset((pageNumber - 1) * pageSize).Scan(result))
+	_, err = checkResult(b.table().Scopes(b.selectColumns(specifyColumns)).Where(condition).Scopes(b.order(orderBy)).Limit(pageSize).Offset((pageNumber - 1) * pageSize).Scan(result))
 	if err != nil {
 		return 0, err
 	}
@@ -174,18 +281,18 @@ func (b BaseMapper[T]) SelectPageByCond(condition *T, orderBy string, pageNumber
 
 // SelectPageByMap 通过指定字段与值查询数据分页查询 解决零值条件问题
 // specifyColumns 指定只需要查询的数据库字段 pageNumber 页码 1开始
-func (b BaseMapper[T]) SelectPageByMap(condition map[string]any, orderBy string, pageNumber, pageSize int, result *[]*T, specifyColumns ...string) (total int64, err error) {
+func (b BaseMapper[T]) SelectPageByMap(condition map[string]any, orderBy string, pageNumber, pageSize int, result *[]*T, specifyColumns ...Column[T]) (total int64, err error) {
 	if pageNumber <= 0 || pageSize <= 0 {
 		return 0, errors.New("pageNumber or pageSize <= 0")
 	}
-	_, err = checkResult(b.rawDB().Table(b.model.TableName()).Where(condition).Count(&total))
+	_, err = checkResult(b.table().Where(condition).Count(&total))
 	if err != nil {
 		return 0, err
 	}
 	if total <= 0 {
 		return 0, nil
 	}
-	_, err = checkResult(b.rawDB().Table(b.model.TableName()).Select(specifyColumns).Where(condition).Order(orderBy).Limit(pageSize).Offset((pageNumber - 1) * pageSize).Scan(result))
+	_, err = checkResult(b.table().Scopes(b.selectColumns(specifyColumns)).Where(condition).Scopes(b.order(orderBy)).Limit(pageSize).Offset((pageNumber - 1) * pageSize).Scan(result))
 	if err != nil {
 		return 0, err
 	}
@@ -193,18 +300,18 @@ func (b BaseMapper[T]) SelectPageByMap(condition map[string]any, orderBy string,
 }
 
 // SelectPageByWhere 通过原始SQL分页查询 rawWhereSql 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
-func (b BaseMapper[T]) SelectPageByWhere(rawWhereSql, orderBy string, pageNumber, pageSize int, result *[]*T, args []any, specifyColumns ...string) (total int64, err error) {
+func (b BaseMapper[T]) SelectPageByWhere(rawWhereSql, orderBy string, pageNumber, pageSize int, result *[]*T, args []any, specifyColumns ...Column[T]) (total int64, err error) {
 	if pageNumber <= 0 || pageSize <= 0 {
 		return 0, errors.New("pageNumber or pageSize <= 0")
 	}
-	_, err = checkResult(b.rawDB().Table(b.model.TableName()).Where(rawWhereSql, args...).Count(&total))
+	_, err = checkResult(b.table().Where(rawWhereSql, args...).Count(&total))
 	if err != nil {
 		return 0, err
 	}
 	if total <= 0 {
 		return 0, nil
 	}
-	_, err = checkResult(b.rawDB().Table(b.model.TableName()).Select(specifyColumns).Where(rawWhereSql, args...).Order(orderBy).Limit(pageSize).Offset((pageNumber - 1) * pageSize).Scan(result))
+	_, err = checkResult(b.table().Scopes(b.selectColumns(specifyColumns)).Where(rawWhereSql, args...).Scopes(b.order(orderBy)).Limit(pageSize).Offset((pageNumber - 1) * pageSize).Scan(result))
 	if err != nil {
 		return 0, err
 	}
@@ -213,7 +320,7 @@ func (b BaseMapper[T]) SelectPageByWhere(rawWhereSql, orderBy string, pageNumber
 
 // SelectPageByGorm 通过原始Gorm分页查询
 func (b BaseMapper[T]) SelectPageByGorm(countRawDb func(*gorm.DB), pageRawDb func(*gorm.DB), result *[]*T) (total int64, err error) {
-	var countDb = b.rawDB().Table(b.model.TableName())
+	var countDb = b.table()
 	countRawDb(countDb)
 	_, err = checkResult(countDb.Count(&total))
 	if err != nil {
@@ -222,7 +329,7 @@ func (b BaseMapper[T]) SelectPageByGorm(countRawDb func(*gorm.DB), pageRawDb fun
 	if total <= 0 {
 		return 0, nil
 	}
-	selectDb := b.rawDB().Table(b.model.TableName())
+	selectDb := b.table()
 	pageRawDb(selectDb)
 	_, err = checkResult(selectDb.Scan(result))
 	if err != nil {
@@ -234,16 +341,26 @@ func (b BaseMapper[T]) SelectPageByGorm(countRawDb func(*gorm.DB), pageRawDb fun
 // Insert 保存数据 零值也将参与保存
 //
 //	exclude 手动指定需要排除的字段名称 数据库字段/结构体字段名称
-func (b BaseMapper[T]) Insert(entity *T, excludeColumns ...string) (int64, error) {
-	var db = b.rawDB()
-	if len(excludeColumns) > 0 {
-		db = db.Omit(excludeColumns...)
+func (b BaseMapper[T]) Insert(entity *T, excludeColumns ...Column[T]) (int64, error) {
+	omits, err := b.columnNames(excludeColumns)
+	if err != nil {
+		return 0, err
+	}
+	if err = b.stampCreate(entity); err != nil {
+		return 0, err
+	}
+	var db = b.audit(b.rawDB())
+	if len(omits) > 0 {
+		db = db.Omit(omits...)
 	}
 	return checkResult(db.Create(entity))
 }
 
 // InsertWithoutZeroField 保存数据 零值将不会参与保存
 func (b BaseMapper[T]) InsertWithoutZeroField(entity *T) (int64, error) {
+	if err := b.stampCreate(entity); err != nil {
+		return 0, err
+	}
 	nonZeroFields, err := reflect.NonZeroFieldName(entity)
 	if err != nil {
 		return 0, err
@@ -252,115 +369,402 @@ func (b BaseMapper[T]) InsertWithoutZeroField(entity *T) (int64, error) {
 		return 0, errors.New("no field to save")
 	}
 	if len(nonZeroFields) == 1 {
-		return checkResult(b.rawDB().Table(b.model.TableName()).Select(nonZeroFields[0]).Create(entity))
+		return checkResult(b.audit(b.rawDB()).Table(b.model.TableName()).Select(nonZeroFields[0]).Create(entity))
 	} else {
 		nonZeroFieldsSlice := coll.SliceCollect(nonZeroFields[1:], func(t string) any {
 			return t
 		})
-		return checkResult(b.rawDB().Table(b.model.TableName()).Select(nonZeroFields[0], nonZeroFieldsSlice...).Create(entity))
+		return checkResult(b.audit(b.rawDB()).Table(b.model.TableName()).Select(nonZeroFields[0], nonZeroFieldsSlice...).Create(entity))
 	}
 }
 
 // InsertBatch 批量新增 零值也将参与保存
 //
 //	exclude 手动指定需要排除的字段名称 数据库字段/结构体字段
-func (b BaseMapper[T]) InsertBatch(entities *[]*T, excludeColumns ...string) (int64, error) {
-	var db = b.rawDB()
-	if len(excludeColumns) > 0 {
-		db = db.Omit(excludeColumns...)
+func (b BaseMapper[T]) InsertBatch(entities *[]*T, excludeColumns ...Column[T]) (int64, error) {
+	omits, err := b.columnNames(excludeColumns)
+	if err != nil {
+		return 0, err
+	}
+	if err = b.stampCreate(*entities...); err != nil {
+		return 0, err
+	}
+	var db = b.audit(b.rawDB())
+	if len(omits) > 0 {
+		db = db.Omit(omits...)
 	}
 	return checkResult(db.Create(entities))
 }
 
+// InsertInBatches 分批新增 零值也将参与保存 每批最多batchSize条 batchSize <= 0 时使用 GormConfig.CreateBatchSize 均未指定时不分批
+// 返回每批的影响行数 某一批失败时已成功的批次不会回滚 需要整体回滚时使用 InsertInBatchesWithTx 或在 Transaction 中调用
+//
+//	exclude 手动指定需要排除的字段名称 数据库字段/结构体字段
+func (b BaseMapper[T]) InsertInBatches(entities *[]*T, batchSize int, excludeColumns ...Column[T]) ([]int64, error) {
+	omits, err := b.columnNames(excludeColumns)
+	if err != nil {
+		return nil, err
+	}
+	if err = b.stampCreate(*entities...); err != nil {
+		return nil, err
+	}
+	return insertInBatches(b.audit(b.rawDB()), *entities, batchSize, omits)
+}
+
+// InsertInBatchesWithTx 在同一事务中分批新增 任一批失败时回滚全部批次 当前Mapper已处于事务中时使用该事务 其他规则同 InsertInBatches
+func (b BaseMapper[T]) InsertInBatchesWithTx(entities *[]*T, batchSize int, excludeColumns ...Column[T]) ([]int64, error) {
+	if b.inTransaction() {
+		return b.InsertInBatches(entities, batchSize, excludeColumns...)
+	}
+	omits, err := b.columnNames(excludeColumns)
+	if err != nil {
+		return nil, err
+	}
+	if err = b.stampCreate(*entities...); err != nil {
+		return nil, err
+	}
+	var rows []int64
+	err = b.rawDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		rows, err = insertInBatches(b.audit(tx), *entities, batchSize, omits)
+		return err
+	})
+	return rows, err
+}
+
+func insertInBatches[T any](db *gorm.DB, entities []*T, batchSize int, excludeColumns []string) ([]int64, error) {
+	if len(entities) == 0 {
+		return nil, errors.New("no entity to save")
+	}
+	if batchSize <= 0 {
+		batchSize = db.CreateBatchSize
+	}
+	if batchSize <= 0 {
+		batchSize = len(entities)
+	}
+	rows := make([]int64, 0, (len(entities)+batchSize-1)/batchSize)
+	for start := 0; start < len(entities); start += batchSize {
+		batch := entities[start:min(start+batchSize, len(entities))]
+		// 每批使用独立的语句 按批次自行拆分 避免gorm再次按全局CreateBatchSize拆分并开启额外事务
+		tx := db.Session(&gorm.Session{CreateBatchSize: batchSize})
+		if len(excludeColumns) > 0 {
+			tx = tx.Omit(excludeColumns...)
+		}
+		affected, err := checkResult(tx.Create(&batch))
+		if err != nil {
+			return rows, err
+		}
+		rows = append(rows, affected)
+	}
+	return rows, nil
+}
+
 // InsertUseMap 通过Map类型保存数据
 func (b BaseMapper[T]) InsertUseMap(entity map[string]any) (int64, error) {
 	if len(entity) == 0 {
 		return 0, errors.New("no field to save")
 	}
-	return checkResult(b.rawDB().Create(entity))
+	if err := b.stampMap(entity, true); err != nil {
+		return 0, err
+	}
+	return checkResult(b.audit(b.rawDB()).Table(b.model.TableName()).Create(entity))
 }
 
 // InsertOrUpdateByPrimaryKey 保存/更新数据 零值也将参与保存
 // exclude 手动指定需要排除的字段名称 数据库字段/结构体字段 (如果触发的是update 创建时间可能会被错误的修改，可以通过excludeColumns来指定排除创建时间字段)
 // 仅根据主键冲突默认支持update 更多操作需要参阅 https://gorm.io/zh_CN/docs/create.html#upsert
-func (b BaseMapper[T]) InsertOrUpdateByPrimaryKey(entity *T, excludeColumns ...string) (int64, error) {
-	var db = b.rawDB()
-	if len(excludeColumns) > 0 {
-		db = db.Omit(excludeColumns...)
+func (b BaseMapper[T]) InsertOrUpdateByPrimaryKey(entity *T, excludeColumns ...Column[T]) (int64, error) {
+	omits, err := b.columnNames(excludeColumns)
+	if err != nil {
+		return 0, err
+	}
+	if err = b.stampCreate(entity); err != nil {
+		return 0, err
+	}
+	if _, err = b.stampUpdate(entity); err != nil {
+		return 0, err
+	}
+	t, err := b.tenant()
+	if err != nil {
+		return 0, err
+	}
+	if t != nil {
+		return b.saveTenant(entity, omits, t)
+	}
+	var db = b.audit(b.rawDB())
+	if len(omits) > 0 {
+		db = db.Omit(omits...)
 	}
 	return checkResult(db.Save(entity))
 }
 
-// UpdateById 通过ID更新含零值字段
+// UpdateById 通过ID更新含零值字段 联合主键时将使用实体中的全部主键值作为条件
 // updateColumns 手动指定需要更新的列
-func (b BaseMapper[T]) UpdateById(updated *T, updateColumns ...string) (int64, error) {
-	return checkResult(b.rawDB().Table(b.model.TableName()).Select(updateColumns).Updates(updated))
+// 模型通过 gorm:"version" 标签声明版本号字段时启用乐观锁 以实体中的版本号作为条件并加1 未更新任何数据时返回 ErrOptimisticLock
+func (b BaseMapper[T]) UpdateById(updated *T, updateColumns ...Column[T]) (int64, error) {
+	columns, err := b.columnNames(updateColumns)
+	if err != nil {
+		return 0, err
+	}
+	lock, err := b.lockEntity(updated)
+	if err != nil {
+		return 0, err
+	}
+	if lock != nil && len(columns) > 0 {
+		columns = append(columns, lock.field.DBName)
+	}
+	updatedBy, err := b.stampUpdate(updated)
+	if err != nil {
+		return lock.check(0, err)
+	}
+	if updatedBy != "" && len(columns) > 0 {
+		columns = append(columns, updatedBy)
+	}
+	db, err := b.whereEntity(b.writeTable(), updated)
+	if err != nil {
+		return lock.check(0, err)
+	}
+	return lock.check(checkResult(lock.apply(db).Select(columns).Updates(updated)))
 }
 
-// UpdateByIdWithoutZeroField 通过ID更新非零值字段
+// UpdateByIdWithoutZeroField 通过ID更新非零值字段 乐观锁规则同 UpdateById
 // allowZeroFiledColumns 额外指定需要更新零值字段
-func (b BaseMapper[T]) UpdateByIdWithoutZeroField(updated *T, allowZeroFiledColumns ...string) (int64, error) {
-	nonZeroFields, err := reflect.NonZeroFieldName(updated)
+func (b BaseMapper[T]) UpdateByIdWithoutZeroField(updated *T, allowZeroFiledColumns ...Column[T]) (int64, error) {
+	allowZeroFields, err := b.columnNames(allowZeroFiledColumns)
+	if err != nil {
+		return 0, err
+	}
+	lock, err := b.lockEntity(updated)
 	if err != nil {
 		return 0, err
 	}
-	if len(allowZeroFiledColumns) > 0 {
-		nonZeroFields = append(nonZeroFields, allowZeroFiledColumns...)
+	if _, err = b.stampUpdate(updated); err != nil {
+		return lock.check(0, err)
+	}
+	nonZeroFields, err := reflect.NonZeroFieldName(updated)
+	if err != nil {
+		return lock.check(0, err)
 	}
+	nonZeroFields = append(nonZeroFields, allowZeroFields...)
 	nonZeroFields = coll.SliceDistinct(nonZeroFields)
-	return checkResult(b.rawDB().Table(b.model.TableName()).Select(nonZeroFields).Updates(updated))
+	db, err := b.whereEntity(b.writeTable(), updated)
+	if err != nil {
+		return lock.check(0, err)
+	}
+	return lock.check(checkResult(lock.apply(db).Select(nonZeroFields).Updates(updated)))
 }
 
-// UpdateByIdUseMap 通过ID更新所有map中指定的列和值
+// UpdateByIdUseMap 通过ID更新所有map中指定的列和值 联合主键时id为 CompositeKey 或携带主键值的模型结构体
+// 启用乐观锁时以map中的版本号作为条件并加1 map中未指定版本号时仅将版本号加1
 func (b BaseMapper[T]) UpdateByIdUseMap(updated map[string]any, id any) (int64, error) {
-	return checkResult(b.rawDB().Table(b.model.TableName()).Where("id = ?", id).Updates(updated))
+	cond, err := b.primaryKeyCond(id)
+	if err != nil {
+		return 0, err
+	}
+	if err = b.stampMap(updated, false); err != nil {
+		return 0, err
+	}
+	lock, err := b.lockMap(updated)
+	if err != nil {
+		return 0, err
+	}
+	return lock.check(checkResult(lock.apply(b.writeTable().Where(cond)).Updates(updated)))
 }
 
 // UpdateByCond 通过条件更新 条件：零值将自动忽略，更新：零值字段将被自动忽略
 // updateColumns 需要指定更新的数据库字段 更新指定字段(支持零值字段)
-func (b BaseMapper[T]) UpdateByCond(updated, condition *T, updateColumns ...string) (int64, error) {
-	return checkResult(b.rawDB().Table(b.model.TableName()).Select(updateColumns).Where(condition).Updates(updated))
+func (b BaseMapper[T]) UpdateByCond(updated, condition *T, updateColumns ...Column[T]) (int64, error) {
+	columns, err := b.columnNames(updateColumns)
+	if err != nil {
+		return 0, err
+	}
+	updatedBy, err := b.stampUpdate(updated)
+	if err != nil {
+		return 0, err
+	}
+	if updatedBy != "" && len(columns) > 0 {
+		columns = append(columns, updatedBy)
+	}
+	return checkResult(b.writeTable().Select(columns).Where(condition).Updates(updated))
 }
 
 // UpdateByCondWithZeroField 通过条件更新，并指定可以更新的零值字段
-func (b BaseMapper[T]) UpdateByCondWithZeroField(updated, condition *T, allowZeroFiledColumns []string) (int64, error) {
-	nonZeroFields, err := reflect.NonZeroFieldName(updated)
+func (b BaseMapper[T]) UpdateByCondWithZeroField(updated, condition *T, allowZeroFiledColumns []Column[T]) (int64, error) {
+	allowZeroFields, err := b.columnNames(allowZeroFiledColumns)
 	if err != nil {
 		return 0, err
 	}
-	if len(allowZeroFiledColumns) > 0 {
-		nonZeroFields = append(nonZeroFields, allowZeroFiledColumns...)
+	if _, err = b.stampUpdate(updated); err != nil {
+		return 0, err
+	}
+	nonZeroFields, err := reflect.NonZeroFieldName(updated)
+	if err != nil {
+		return 0, err
 	}
+	nonZeroFields = append(nonZeroFields, allowZeroFields...)
 	nonZeroFields = coll.SliceDistinct(nonZeroFields)
-	return checkResult(b.rawDB().Table(b.model.TableName()).Select(nonZeroFields).Where(condition).Updates(updated))
+	return checkResult(b.writeTable().Select(nonZeroFields).Where(condition).Updates(updated))
 }
 
 // UpdateByMap 通过Map类型条件更新
 func (b BaseMapper[T]) UpdateByMap(updated, condition map[string]any) (int64, error) {
-	return checkResult(b.rawDB().Table(b.model.TableName()).Where(condition).Updates(updated))
+	if err := b.stampMap(updated, false); err != nil {
+		return 0, err
+	}
+	return checkResult(b.writeTable().Where(condition).Updates(updated))
 }
 
 // UpdateByWhere 通过原始SQL查询条件，更新非零实体字段 Where SQL查询 只需要输入SQL语句和参数 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
 func (b BaseMapper[T]) UpdateByWhere(updated *T, rawWhereSql string, args ...any) (int64, error) {
-	return checkResult(b.rawDB().Table(b.model.TableName()).Where(rawWhereSql, args...).Updates(updated))
+	if _, err := b.stampUpdate(updated); err != nil {
+		return 0, err
+	}
+	return checkResult(b.writeTable().Where(rawWhereSql, args...).Updates(updated))
 }
 
-// DeleteById 通过ID删除相关数据
+// DeleteById 通过ID删除相关数据 联合主键时id元素为 CompositeKey 或携带主键值的模型结构体
+// 模型声明软删除字段时 Delete* 仅标记删除 物理删除使用 HardDelete*
 func (b BaseMapper[T]) DeleteById(id ...any) (int64, error) {
-	return checkResult(b.rawDB().Delete(b.model, id))
+	cond, err := b.primaryKeyCond(id...)
+	if err != nil {
+		return 0, err
+	}
+	return b.delete(b.writeTable().Where(cond))
 }
 
 // DeleteByCond 通过条件删除 零值字段将被自动忽略
 func (b BaseMapper[T]) DeleteByCond(condition *T) (int64, error) {
-	return checkResult(b.rawDB().Table(b.model.TableName()).Where(condition).Delete(b.model))
+	return b.delete(b.writeTable().Where(condition))
 }
 
 // DeleteByWhere 通过原始SQL删除相关数据 Where SQL查询 只需要输入SQL语句和参数 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
 func (b BaseMapper[T]) DeleteByWhere(rawWhereSql string, args ...any) (int64, error) {
-	return checkResult(b.rawDB().Where(rawWhereSql, args...).Delete(b.model))
+	return b.delete(b.writeTable().Where(rawWhereSql, args...))
 }
 
 // DeleteByMap 通过Map类型条件删除
 func (b BaseMapper[T]) DeleteByMap(condition map[string]any) (int64, error) {
-	return checkResult(b.rawDB().Where(condition).Delete(b.model))
+	return b.delete(b.writeTable().Where(condition))
+}
+
+// queryDB 通过查询条件构造器构建gorm.DB full为true时附带排序及查询字段
+func (b BaseMapper[T]) queryDB(query *Query[T], full bool) (*gorm.DB, error) {
+	return b.buildQuery(b.table(), query, full)
+}
+
+func (b BaseMapper[T]) buildQuery(db *gorm.DB, query *Query[T], full bool) (*gorm.DB, error) {
+	sch, err := b.schema()
+	if err != nil {
+		return nil, err
+	}
+	where, err := query.where(sch)
+	if err != nil {
+		return nil, err
+	}
+	if where != nil {
+		db = db.Where(where)
+	}
+	if !full {
+		return db, nil
+	}
+	orders, err := query.orderBy(sch)
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		db = db.Order(order)
+	}
+	columns, err := query.selectColumns(sch)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) > 0 {
+		db = db.Select(columns)
+	}
+	return db, nil
+}
+
+// SelectOneByQuery 通过查询条件构造器查询单条数据
+func (b BaseMapper[T]) SelectOneByQuery(query *Query[T], result *T) (int64, error) {
+	db, err := b.queryDB(query, true)
+	if err != nil {
+		return 0, err
+	}
+	return checkResult(db.Limit(1).Scan(result))
+}
+
+// SelectByQuery 通过查询条件构造器查询数据
+func (b BaseMapper[T]) SelectByQuery(query *Query[T], result *[]*T) (int64, error) {
+	db, err := b.queryDB(query, true)
+	if err != nil {
+		return 0, err
+	}
+	return checkResult(db.Scan(result))
+}
+
+// CountByQuery 通过查询条件构造器查询数据总数
+func (b BaseMapper[T]) CountByQuery(query *Query[T]) (int64, error) {
+	db, err := b.queryDB(query, false)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	_, err = checkResult(db.Count(&count))
+	return count, err
+}
+
+// SelectPageByQuery 通过查询条件构造器分页查询 pageNumber 页码 1开始
+func (b BaseMapper[T]) SelectPageByQuery(query *Query[T], pageNumber, pageSize int, result *[]*T) (total int64, err error) {
+	if pageNumber <= 0 || pageSize <= 0 {
+		return 0, errors.New("pageNumber or pageSize <= 0")
+	}
+	total, err = b.CountByQuery(query)
+	if err != nil {
+		return 0, err
+	}
+	if total <= 0 {
+		return 0, nil
+	}
+	db, err := b.queryDB(query, true)
+	if err != nil {
+		return 0, err
+	}
+	_, err = checkResult(db.Limit(pageSize).Offset((pageNumber - 1) * pageSize).Scan(result))
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// UpdateByQuery 通过查询条件构造器更新所有map中指定的列和值 map的key支持数据库字段名或结构体字段名
+func (b BaseMapper[T]) UpdateByQuery(updated map[string]any, query *Query[T]) (int64, error) {
+	sch, err := b.schema()
+	if err != nil {
+		return 0, err
+	}
+	values := make(map[string]any, len(updated)+1)
+	for column, value := range updated {
+		name, err := lookupColumn(sch, column)
+		if err != nil {
+			return 0, err
+		}
+		values[name] = value
+	}
+	if err = b.stampMap(values, false); err != nil {
+		return 0, err
+	}
+	db, err := b.buildQuery(b.writeTable(), query, false)
+	if err != nil {
+		return 0, err
+	}
+	return checkResult(db.Updates(values))
+}
+
+// DeleteByQuery 通过查询条件构造器删除数据 无查询条件时将拒绝执行
+func (b BaseMapper[T]) DeleteByQuery(query *Query[T]) (int64, error) {
+	db, err := b.buildQuery(b.writeTable(), query, false)
+	if err != nil {
+		return 0, err
+	}
+	return b.delete(db)
 }
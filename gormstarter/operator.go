@@ -0,0 +1,135 @@
+package gormstarter
+
+import (
+	"context"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+// 数据源对应的操作人获取函数 key: 数据源名称
+var operatorExtractors = make(map[string]func(ctx context.Context) any)
+
+// operatorFields 当前操作人及模型的操作人字段
+// 通过 gorm:"createdBy" / gorm:"updatedBy" / gorm:"deletedBy" 标签或数据库字段名 created_by / updated_by / deleted_by 声明
+type operatorFields struct {
+	operator  any
+	createdBy *schema.Field
+	updatedBy *schema.Field
+	deletedBy *schema.Field
+}
+
+// operatorFields 获取当前操作人及模型的操作人字段 未配置 GormConfig.OperatorExtractor、未获取到操作人或模型未声明操作人字段时返回nil
+func (b BaseMapper[T]) operatorFields() (*operatorFields, error) {
+	extractor := operatorExtractors[b.DataSource()]
+	if extractor == nil {
+		return nil, nil
+	}
+	operator := extractor(b.context())
+	if operator == nil {
+		return nil, nil
+	}
+	sch, err := b.schema()
+	if err != nil {
+		return nil, err
+	}
+	fields := &operatorFields{
+		operator:  operator,
+		createdBy: operatorField(sch, "CREATEDBY", "created_by"),
+		updatedBy: operatorField(sch, "UPDATEDBY", "updated_by"),
+		deletedBy: operatorField(sch, "DELETEDBY", "deleted_by"),
+	}
+	if fields.createdBy == nil && fields.updatedBy == nil && fields.deletedBy == nil {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// operatorField 优先使用标签声明的字段
+func operatorField(sch *schema.Schema, tag, column string) *schema.Field {
+	for _, field := range sch.Fields {
+		if _, ok := field.TagSettings[tag]; ok {
+			return field
+		}
+	}
+	return sch.LookUpField(column)
+}
+
+// stampCreate 新增时填充租户、创建人及更新人 已有值的操作人字段不会被覆盖
+func (b BaseMapper[T]) stampCreate(entities ...*T) error {
+	if err := b.stampTenant(entities...); err != nil {
+		return err
+	}
+	o, err := b.operatorFields()
+	if err != nil || o == nil {
+		return err
+	}
+	for _, entity := range entities {
+		rv := reflect.ValueOf(entity).Elem()
+		for _, field := range []*schema.Field{o.createdBy, o.updatedBy} {
+			if field == nil {
+				continue
+			}
+			if _, zero := field.ValueOf(b.context(), rv); !zero {
+				continue
+			}
+			if err = field.Set(b.context(), rv, o.operator); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// stampUpdate 更新时填充租户及更新人 返回更新人字段名 用于追加到指定更新的字段中 模型未声明更新人字段时返回空字符串
+func (b BaseMapper[T]) stampUpdate(entity *T) (string, error) {
+	if err := b.stampTenant(entity); err != nil {
+		return "", err
+	}
+	o, err := b.operatorFields()
+	if err != nil || o == nil || o.updatedBy == nil {
+		return "", err
+	}
+	if err = o.updatedBy.Set(b.context(), reflect.ValueOf(entity).Elem(), o.operator); err != nil {
+		return "", err
+	}
+	return o.updatedBy.DBName, nil
+}
+
+// stampMap 填充map中的租户字段及未指定的操作人字段 create 新增时填充创建人及更新人 否则仅填充更新人
+func (b BaseMapper[T]) stampMap(values map[string]any, create bool) error {
+	if err := b.stampTenantMap(values, create); err != nil {
+		return err
+	}
+	o, err := b.operatorFields()
+	if err != nil || o == nil {
+		return err
+	}
+	fields := []*schema.Field{o.updatedBy}
+	if create {
+		fields = append(fields, o.createdBy)
+	}
+	for _, field := range fields {
+		if field == nil {
+			continue
+		}
+		if _, ok := values[field.DBName]; ok {
+			continue
+		}
+		if _, ok := values[field.Name]; ok {
+			continue
+		}
+		values[field.DBName] = o.operator
+	}
+	return nil
+}
+
+// stampDelete 软删除时填充删除人
+func (b BaseMapper[T]) stampDelete(values map[string]any) error {
+	o, err := b.operatorFields()
+	if err != nil || o == nil || o.deletedBy == nil {
+		return err
+	}
+	values[o.deletedBy.DBName] = o.operator
+	return nil
+}
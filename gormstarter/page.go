@@ -0,0 +1,251 @@
+package gormstarter
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+const (
+	CountExact      CountStrategy = iota // 查询精确总数 先查询总数再查询数据
+	CountNone                            // 不查询总数 通过多查询一条数据判断是否存在下一页
+	CountEstimate                        // 查询估算总数 mysql/postgres 通过 EXPLAIN 获取估算行数 mysql执行计划包含多行时及其他数据库使用精确总数
+	CountConcurrent                      // 查询精确总数 总数与数据并发查询 事务内将退化为顺序查询
+)
+
+// CountStrategy 分页查询总数的策略
+type CountStrategy int
+
+// PageOptions 分页查询选项
+type PageOptions struct {
+	Count CountStrategy // 总数查询策略 默认为 CountExact
+}
+
+// Page 分页结果
+type Page[T IBaseModel] struct {
+	Records    []*T  `json:"records"`
+	PageNumber int   `json:"pageNumber"`
+	PageSize   int   `json:"pageSize"`
+	Total      int64 `json:"total"`      // 数据总数 CountNone 时为 -1 CountEstimate 时为估算值
+	TotalPages int64 `json:"totalPages"` // 总页数 CountNone 时为 -1
+	HasNext    bool  `json:"hasNext"`
+	HasPrev    bool  `json:"hasPrev"`
+}
+
+// FindPage 通过查询条件构造器分页查询 pageNumber 页码 1开始
+func (b BaseMapper[T]) FindPage(query *Query[T], pageNumber, pageSize int, opts ...PageOptions) (*Page[T], error) {
+	return b.findPage(func() (*gorm.DB, error) {
+		return b.queryDB(query, false)
+	}, func() (*gorm.DB, error) {
+		return b.queryDB(query, true)
+	}, pageNumber, pageSize, opts)
+}
+
+// FindPageByCond 通过条件分页查询 零值字段将被自动忽略
+// specifyColumns 指定只需要查询的数据库字段 pageNumber 页码 1开始
+func (b BaseMapper[T]) FindPageByCond(condition *T, orderBy string, pageNumber, pageSize int, opts []PageOptions, specifyColumns ...Column[T]) (*Page[T], error) {
+	return b.findPage(func() (*gorm.DB, error) {
+		return b.table().Where(condition), nil
+	}, func() (*gorm.DB, error) {
+		return b.table().Scopes(b.selectColumns(specifyColumns)).Where(condition).Scopes(b.order(orderBy)), nil
+	}, pageNumber, pageSize, opts)
+}
+
+// FindPageByMap 通过指定字段与值分页查询 解决零值条件问题
+// specifyColumns 指定只需要查询的数据库字段 pageNumber 页码 1开始
+func (b BaseMapper[T]) FindPageByMap(condition map[string]any, orderBy string, pageNumber, pageSize int, opts []PageOptions, specifyColumns ...Column[T]) (*Page[T], error) {
+	return b.findPage(func() (*gorm.DB, error) {
+		return b.table().Where(condition), nil
+	}, func() (*gorm.DB, error) {
+		return b.table().Scopes(b.selectColumns(specifyColumns)).Where(condition).Scopes(b.order(orderBy)), nil
+	}, pageNumber, pageSize, opts)
+}
+
+// FindPageByWhere 通过原始SQL分页查询 rawWhereSql 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
+// specifyColumns 指定只需要查询的数据库字段 pageNumber 页码 1开始
+func (b BaseMapper[T]) FindPageByWhere(rawWhereSql, orderBy string, pageNumber, pageSize int, args []any, opts []PageOptions, specifyColumns ...Column[T]) (*Page[T], error) {
+	return b.findPage(func() (*gorm.DB, error) {
+		return b.table().Where(rawWhereSql, args...), nil
+	}, func() (*gorm.DB, error) {
+		return b.table().Scopes(b.selectColumns(specifyColumns)).Where(rawWhereSql, args...).Scopes(b.order(orderBy)), nil
+	}, pageNumber, pageSize, opts)
+}
+
+// findPage 按总数查询策略执行分页查询 countDB/selectDB 每次调用都需要返回全新的查询
+func (b BaseMapper[T]) findPage(countDB, selectDB func() (*gorm.DB, error), pageNumber, pageSize int, opts []PageOptions) (*Page[T], error) {
+	if pageNumber <= 0 || pageSize <= 0 {
+		return nil, errors.New("pageNumber or pageSize <= 0")
+	}
+	var options PageOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	page := &Page[T]{PageNumber: pageNumber, PageSize: pageSize, Total: -1, TotalPages: -1, HasPrev: pageNumber > 1}
+	selectRecords := func(limit int) error {
+		db, err := selectDB()
+		if err != nil {
+			return err
+		}
+		records := make([]*T, 0, limit)
+		if _, err = checkResult(db.Limit(limit).Offset((pageNumber - 1) * pageSize).Scan(&records)); err != nil {
+			return err
+		}
+		page.Records = records
+		return nil
+	}
+	count := func() (int64, error) {
+		db, err := countDB()
+		if err != nil {
+			return 0, err
+		}
+		var total int64
+		_, err = checkResult(db.Count(&total))
+		return total, err
+	}
+
+	strategy := options.Count
+	if strategy == CountConcurrent && b.inTransaction() {
+		// 事务绑定单个连接 无法并发查询
+		strategy = CountExact
+	}
+	switch strategy {
+	case CountExact:
+		total, err := count()
+		if err != nil {
+			return nil, err
+		}
+		page.setTotal(total)
+		if total <= int64((pageNumber-1)*pageSize) {
+			page.Records = make([]*T, 0)
+			return page, nil
+		}
+		if err = selectRecords(pageSize); err != nil {
+			return nil, err
+		}
+	case CountConcurrent:
+		var total int64
+		var countErr, selectErr error
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			total, countErr = count()
+		}()
+		selectErr = selectRecords(pageSize)
+		wg.Wait()
+		if countErr != nil {
+			return nil, countErr
+		}
+		if selectErr != nil {
+			return nil, selectErr
+		}
+		page.setTotal(total)
+	case CountNone, CountEstimate:
+		if strategy == CountEstimate {
+			db, err := countDB()
+			if err != nil {
+				return nil, err
+			}
+			total, err := b.estimateCount(db)
+			if err != nil {
+				return nil, err
+			}
+			page.setTotal(total)
+		}
+		if err := selectRecords(pageSize + 1); err != nil {
+			return nil, err
+		}
+		page.HasNext = len(page.Records) > pageSize
+		if page.HasNext {
+			page.Records = page.Records[:pageSize]
+		}
+	default:
+		return nil, errors.New("not supported count strategy " + strconv.Itoa(int(strategy)))
+	}
+	return page, nil
+}
+
+func (p *Page[T]) setTotal(total int64) {
+	p.Total = total
+	p.TotalPages = (total + int64(p.PageSize) - 1) / int64(p.PageSize)
+	p.HasNext = int64(p.PageNumber) < p.TotalPages
+}
+
+// inTransaction 当前Mapper是否处于事务中
+func (b BaseMapper[T]) inTransaction() bool {
+	return b.tx != nil || TxFromContext(b.ctx, b.DataSource()) != nil
+}
+
+// estimateCount 通过 EXPLAIN 获取估算行数 不支持的数据库使用精确总数
+func (b BaseMapper[T]) estimateCount(db *gorm.DB) (int64, error) {
+	var total int64
+	dialect := db.Dialector.Name()
+	if dialect != string(DBTypeMySQL) && dialect != string(DBTypePostgres) {
+		_, err := checkResult(db.Count(&total))
+		return total, err
+	}
+	stmt := db.Session(&gorm.Session{DryRun: true}).Select("*").Find(&[]*T{}).Statement
+	if stmt.Error != nil {
+		return 0, stmt.Error
+	}
+	if dialect == string(DBTypePostgres) {
+		var plan string
+		if err := b.rawDB().Raw("EXPLAIN (FORMAT JSON) "+stmt.SQL.String(), stmt.Vars...).Scan(&plan).Error; err != nil {
+			return 0, err
+		}
+		var plans []struct {
+			Plan struct {
+				Rows float64 `json:"Plan Rows"`
+			} `json:"Plan"`
+		}
+		if err := json.Unmarshal([]byte(plan), &plans); err != nil || len(plans) == 0 {
+			return 0, errors.New("unexpected explain result " + plan)
+		}
+		return int64(plans[0].Plan.Rows), nil
+	}
+	var explains []map[string]any
+	if err := b.rawDB().Raw("EXPLAIN "+stmt.SQL.String(), stmt.Vars...).Scan(&explains).Error; err != nil {
+		return 0, err
+	}
+	rows, ok, err := explainRows(explains)
+	if err != nil || ok {
+		return rows, err
+	}
+	_, err = checkResult(db.Count(&total))
+	return total, err
+}
+
+// explainRows 获取mysql EXPLAIN结果中的估算行数 联表、子查询等产生多行执行计划时无法估算 返回false
+func explainRows(explains []map[string]any) (int64, bool, error) {
+	if len(explains) != 1 {
+		return 0, false, nil
+	}
+	for key, value := range explains[0] {
+		if strings.EqualFold(key, "rows") && value != nil {
+			rows, err := strconv.ParseInt(toString(value), 10, 64)
+			if err != nil {
+				return 0, false, err
+			}
+			return rows, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func toString(value any) string {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	}
+	return ""
+}
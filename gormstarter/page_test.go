@@ -0,0 +1,23 @@
+package gormstarter
+
+import "testing"
+
+func TestExplainRows(t *testing.T) {
+	rows, ok, err := explainRows([]map[string]any{{"id": int64(1), "table": "demo_teacher", "rows": []byte("120")}})
+	if err != nil || !ok || rows != 120 {
+		t.Fatal(rows, ok, err)
+	}
+	// 联表查询的执行计划包含多行 需要使用精确总数
+	if _, ok, err = explainRows([]map[string]any{
+		{"id": int64(1), "table": "demo_student", "rows": int64(3)},
+		{"id": int64(1), "table": "demo_teacher", "rows": int64(1000)},
+	}); err != nil || ok {
+		t.Fatal(ok, err)
+	}
+	if _, ok, err = explainRows([]map[string]any{{"id": int64(1), "rows": nil, "Extra": "Impossible WHERE"}}); err != nil || ok {
+		t.Fatal(ok, err)
+	}
+	if _, ok, err = explainRows(nil); err != nil || ok {
+		t.Fatal(ok, err)
+	}
+}
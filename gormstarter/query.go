@@ -0,0 +1,277 @@
+package gormstarter
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrUnknownColumn 查询条件中的字段不属于当前模型
+var ErrUnknownColumn = errors.New("unknown column")
+
+const (
+	operatorEq        = "="
+	operatorNe        = "<>"
+	operatorGt        = ">"
+	operatorGe        = ">="
+	operatorLt        = "<"
+	operatorLe        = "<="
+	operatorBetween   = "BETWEEN"
+	operatorIn        = "IN"
+	operatorNotIn     = "NOT IN"
+	operatorLike      = "LIKE"
+	operatorIsNull    = "IS NULL"
+	operatorIsNotNull = "IS NOT NULL"
+)
+
+// Query 类型安全的查询条件构造器 字段支持 Column 描述、数据库字段名或结构体字段名 执行时将基于模型的gorm schema校验 未知字段返回 ErrUnknownColumn
+// 同一层级的条件之间使用 AND 连接 通过 And / Or 构建分组条件
+type Query[T IBaseModel] struct {
+	conditions []queryCondition[T]
+	orders     []queryOrder[T]
+	selects    []Column[T]
+}
+
+type queryCondition[T IBaseModel] struct {
+	column   Column[T]
+	operator string
+	values   []any
+	and      *Query[T]
+	or       []*Query[T]
+}
+
+type queryOrder[T IBaseModel] struct {
+	column Column[T]
+	desc   bool
+}
+
+// NewQuery 创建查询条件构造器
+func NewQuery[T IBaseModel]() *Query[T] {
+	return &Query[T]{}
+}
+
+func (q *Query[T]) add(column Column[T], operator string, values ...any) *Query[T] {
+	q.conditions = append(q.conditions, queryCondition[T]{column: column, operator: operator, values: values})
+	return q
+}
+
+// Eq column = value
+func (q *Query[T]) Eq(column Column[T], value any) *Query[T] {
+	return q.add(column, operatorEq, value)
+}
+
+// Ne column <> value
+func (q *Query[T]) Ne(column Column[T], value any) *Query[T] {
+	return q.add(column, operatorNe, value)
+}
+
+// Gt column > value
+func (q *Query[T]) Gt(column Column[T], value any) *Query[T] {
+	return q.add(column, operatorGt, value)
+}
+
+// Ge column >= value
+func (q *Query[T]) Ge(column Column[T], value any) *Query[T] {
+	return q.add(column, operatorGe, value)
+}
+
+// Lt column < value
+func (q *Query[T]) Lt(column Column[T], value any) *Query[T] {
+	return q.add(column, operatorLt, value)
+}
+
+// Le column <= value
+func (q *Query[T]) Le(column Column[T], value any) *Query[T] {
+	return q.add(column, operatorLe, value)
+}
+
+// Between column BETWEEN start AND end
+func (q *Query[T]) Between(column Column[T], start, end any) *Query[T] {
+	return q.add(column, operatorBetween, start, end)
+}
+
+// In column IN (values...)
+func (q *Query[T]) In(column Column[T], values ...any) *Query[T] {
+	return q.add(column, operatorIn, values...)
+}
+
+// NotIn column NOT IN (values...)
+func (q *Query[T]) NotIn(column Column[T], values ...any) *Query[T] {
+	return q.add(column, operatorNotIn, values...)
+}
+
+// Like column LIKE value 通配符需要调用方自行指定 例如 %name%
+func (q *Query[T]) Like(column Column[T], value string) *Query[T] {
+	return q.add(column, operatorLike, value)
+}
+
+// IsNull column IS NULL
+func (q *Query[T]) IsNull(column Column[T]) *Query[T] {
+	return q.add(column, operatorIsNull)
+}
+
+// IsNotNull column IS NOT NULL
+func (q *Query[T]) IsNotNull(column Column[T]) *Query[T] {
+	return q.add(column, operatorIsNotNull)
+}
+
+// And 添加一组使用括号包裹的条件 与当前条件使用 AND 连接
+func (q *Query[T]) And(fn func(q *Query[T])) *Query[T] {
+	group := NewQuery[T]()
+	fn(group)
+	q.conditions = append(q.conditions, queryCondition[T]{and: group})
+	return q
+}
+
+// Or 添加多组使用 OR 连接的条件 整体与当前条件使用 AND 连接 例如 a = 1 AND ((b = 2) OR (c = 3))
+func (q *Query[T]) Or(fns ...func(q *Query[T])) *Query[T] {
+	groups := make([]*Query[T], len(fns))
+	for i, fn := range fns {
+		groups[i] = NewQuery[T]()
+		fn(groups[i])
+	}
+	q.conditions = append(q.conditions, queryCondition[T]{or: groups})
+	return q
+}
+
+// OrderByAsc 按指定字段升序排序
+func (q *Query[T]) OrderByAsc(columns ...Column[T]) *Query[T] {
+	for _, column := range columns {
+		q.orders = append(q.orders, queryOrder[T]{column: column})
+	}
+	return q
+}
+
+// OrderByDesc 按指定字段降序排序
+func (q *Query[T]) OrderByDesc(columns ...Column[T]) *Query[T] {
+	for _, column := range columns {
+		q.orders = append(q.orders, queryOrder[T]{column: column, desc: true})
+	}
+	return q
+}
+
+// Select 指定只需要查询的字段
+func (q *Query[T]) Select(columns ...Column[T]) *Query[T] {
+	q.selects = append(q.selects, columns...)
+	return q
+}
+
+// lookupColumn 通过数据库字段名或结构体字段名获取数据库字段名
+func lookupColumn(sch *schema.Schema, column string) (string, error) {
+	field := sch.LookUpField(column)
+	if field == nil || field.DBName == "" {
+		return "", fmt.Errorf("%w %s of model %s", ErrUnknownColumn, column, sch.Name)
+	}
+	return field.DBName, nil
+}
+
+// where 构建查询条件 无条件时返回nil
+func (q *Query[T]) where(sch *schema.Schema) (clause.Expression, error) {
+	if q == nil || len(q.conditions) == 0 {
+		return nil, nil
+	}
+	exprs := make([]clause.Expression, 0, len(q.conditions))
+	for _, condition := range q.conditions {
+		var expr clause.Expression
+		var err error
+		switch {
+		case condition.and != nil:
+			expr, err = condition.and.where(sch)
+		case condition.or != nil:
+			orExprs := make([]clause.Expression, 0, len(condition.or))
+			for _, group := range condition.or {
+				groupExpr, err := group.where(sch)
+				if err != nil {
+					return nil, err
+				}
+				if groupExpr != nil {
+					orExprs = append(orExprs, groupExpr)
+				}
+			}
+			if len(orExprs) > 0 {
+				expr = clause.Or(orExprs...)
+			}
+		default:
+			expr, err = condition.expression(sch)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if expr != nil {
+			exprs = append(exprs, expr)
+		}
+	}
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	return clause.And(exprs...), nil
+}
+
+func (c queryCondition[T]) expression(sch *schema.Schema) (clause.Expression, error) {
+	name, err := lookupColumn(sch, string(c.column))
+	if err != nil {
+		return nil, err
+	}
+	column := clause.Column{Name: name}
+	switch c.operator {
+	case operatorEq:
+		return clause.Eq{Column: column, Value: c.values[0]}, nil
+	case operatorNe:
+		return clause.Neq{Column: column, Value: c.values[0]}, nil
+	case operatorGt:
+		return clause.Gt{Column: column, Value: c.values[0]}, nil
+	case operatorGe:
+		return clause.Gte{Column: column, Value: c.values[0]}, nil
+	case operatorLt:
+		return clause.Lt{Column: column, Value: c.values[0]}, nil
+	case operatorLe:
+		return clause.Lte{Column: column, Value: c.values[0]}, nil
+	case operatorBetween:
+		return clause.Expr{SQL: "? BETWEEN ? AND ?", Vars: []any{column, c.values[0], c.values[1]}}, nil
+	case operatorIn:
+		return clause.IN{Column: column, Values: c.values}, nil
+	case operatorNotIn:
+		return clause.Not(clause.IN{Column: column, Values: c.values}), nil
+	case operatorLike:
+		return clause.Like{Column: column, Value: c.values[0]}, nil
+	case operatorIsNull:
+		return clause.Eq{Column: column, Value: nil}, nil
+	case operatorIsNotNull:
+		return clause.Neq{Column: column, Value: nil}, nil
+	}
+	return nil, errors.New("not supported query operator " + c.operator)
+}
+
+// orderBy 构建排序条件
+func (q *Query[T]) orderBy(sch *schema.Schema) ([]clause.OrderByColumn, error) {
+	if q == nil {
+		return nil, nil
+	}
+	columns := make([]clause.OrderByColumn, 0, len(q.orders))
+	for _, order := range q.orders {
+		name, err := lookupColumn(sch, string(order.column))
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, clause.OrderByColumn{Column: clause.Column{Name: name}, Desc: order.desc})
+	}
+	return columns, nil
+}
+
+// selectColumns 构建查询字段
+func (q *Query[T]) selectColumns(sch *schema.Schema) ([]string, error) {
+	if q == nil {
+		return nil, nil
+	}
+	columns := make([]string, 0, len(q.selects))
+	for _, column := range q.selects {
+		name, err := lookupColumn(sch, string(column))
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, nil
+}
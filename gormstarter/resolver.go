@@ -0,0 +1,116 @@
+package gormstarter
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const (
+	ReplicaPolicyRandom           ReplicaPolicy = "random"             // 随机选择从库
+	ReplicaPolicyRoundRobin       ReplicaPolicy = "round-robin"        // 轮询选择从库
+	ReplicaPolicyStrictRoundRobin ReplicaPolicy = "strict-round-robin" // 严格轮询选择从库
+)
+
+type ReplicaPolicy string
+
+// ReplicaConfig 从库配置 未指定的字段将沿用主库配置
+type ReplicaConfig struct {
+	DSN      string // 完整的数据库连接串 优先级高于其他字段 主库使用驱动原生格式的DSN时必须指定
+	Username string
+	Password string
+	Host     string
+	Port     uint
+}
+
+func (p ReplicaPolicy) policy() (dbresolver.Policy, error) {
+	switch p {
+	case "", ReplicaPolicyRandom:
+		return dbresolver.RandomPolicy{}, nil
+	case ReplicaPolicyRoundRobin:
+		return dbresolver.RoundRobinPolicy(), nil
+	case ReplicaPolicyStrictRoundRobin:
+		return dbresolver.StrictRoundRobinPolicy(), nil
+	}
+	return nil, errors.New("not supported replica policy " + string(p))
+}
+
+func replicaName(config *GormConfig, index int) string {
+	return config.Name + "-replica-" + strconv.Itoa(index+1)
+}
+
+// replicaConfig 基于主库配置生成从库配置
+func replicaConfig(config *GormConfig, index int) (*GormConfig, error) {
+	replica := config.Replicas[index]
+	replicaConfig := *config
+	replicaConfig.Name = replicaName(config, index)
+	replicaConfig.Replicas = nil
+	if replica.DSN != "" {
+		replicaConfig.DSN = replica.DSN
+		replicaConfig.dsnParams = nil
+		if err := resolveDSN(&replicaConfig); err != nil {
+			return nil, err
+		}
+		return &replicaConfig, nil
+	}
+	if config.DSN != "" {
+		return nil, errors.New("replica of data source " + config.Name + " requires dsn")
+	}
+	if config.DBType == DBTypeSQLite && isSQLiteMemory(config) {
+		// 内存数据库的从库将以自身名称打开一个新的空数据库
+		return nil, errors.New("replica of in-memory sqlite data source " + config.Name + " is not supported")
+	}
+	if replica.Username != "" {
+		replicaConfig.Username = replica.Username
+	}
+	if replica.Password != "" {
+		replicaConfig.Password = replica.Password
+	}
+	if replica.Host != "" {
+		replicaConfig.Host = replica.Host
+	}
+	if replica.Port != 0 {
+		replicaConfig.Port = replica.Port
+	}
+	return &replicaConfig, nil
+}
+
+// useReplicas 注册读写分离插件 返回所有从库连接
+func useReplicas(gormDB *gorm.DB, config *GormConfig) ([]*sql.DB, error) {
+	policy, err := config.ReplicaPolicy.policy()
+	if err != nil {
+		return nil, err
+	}
+	dialectors := make([]gorm.Dialector, len(config.Replicas))
+	for i := range config.Replicas {
+		replicaConfig, err := replicaConfig(config, i)
+		if err != nil {
+			return nil, err
+		}
+		if dialectors[i], err = openDialector(replicaConfig); err != nil {
+			return nil, err
+		}
+	}
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas: dialectors,
+		Policy:   policy,
+	})
+	if err = gormDB.Use(resolver); err != nil {
+		return nil, err
+	}
+	primary, err := gormDB.DB()
+	if err != nil {
+		return nil, err
+	}
+	var replicas []*sql.DB
+	err = resolver.Call(func(connPool gorm.ConnPool) error {
+		if sqlDb, ok := connPool.(*sql.DB); ok && sqlDb != primary {
+			replicas = append(replicas, sqlDb)
+		}
+		return nil
+	})
+	return replicas, err
+}
@@ -0,0 +1,28 @@
+package gormstarter
+
+import "testing"
+
+func TestSQLiteReplicaConfig(t *testing.T) {
+	config := &GormConfig{Name: "sqlite", DBType: DBTypeSQLite, Replicas: []ReplicaConfig{{}}}
+	if _, err := replicaConfig(config, 0); err == nil {
+		t.Fatal("expect in-memory sqlite replica error")
+	}
+	config.SQLiteFile = sqliteMemory
+	if _, err := replicaConfig(config, 0); err == nil {
+		t.Fatal("expect in-memory sqlite replica error")
+	}
+	config.SQLiteFile = "./data.db"
+	replica, err := replicaConfig(config, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replica.SQLiteFile != config.SQLiteFile || replica.Name != "sqlite-replica-1" {
+		t.Fatalf("unexpected replica %+v", replica)
+	}
+	// 显式指定DSN时使用该DSN
+	config.SQLiteFile = ""
+	config.Replicas = []ReplicaConfig{{DSN: "file:sqlite?mode=memory&cache=shared"}}
+	if replica, err = replicaConfig(config, 0); err != nil || replica.DSN != config.Replicas[0].DSN {
+		t.Fatal(replica, err)
+	}
+}
@@ -0,0 +1,88 @@
+package gormstarter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrMissingPrimaryKey 模型未声明主键
+var ErrMissingPrimaryKey = errors.New("model has no primary key")
+
+// schema 解析当前Mapper对应模型的gorm schema
+func (b BaseMapper[T]) schema() (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: b.rawDB()}
+	if err := stmt.Parse(&b.model); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// primaryKeyCond 通过主键值构建查询条件
+// 单主键时 ids 为主键值；联合主键时 ids 为 CompositeKey 或携带主键值的模型结构体 多个值时使用 (a,b) IN ((?,?),(?,?)) 条件
+func (b BaseMapper[T]) primaryKeyCond(ids ...any) (clause.Expression, error) {
+	sch, err := b.schema()
+	if err != nil {
+		return nil, err
+	}
+	if len(sch.PrimaryFields) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingPrimaryKey, sch.Name)
+	}
+	values := make([]any, 0, len(ids))
+	for _, id := range ids {
+		keyValues, err := b.primaryKeyValues(sch, id)
+		if err != nil {
+			return nil, err
+		}
+		if len(keyValues) == 1 {
+			values = append(values, keyValues[0])
+		} else {
+			values = append(values, keyValues)
+		}
+	}
+	if len(sch.PrimaryFields) == 1 {
+		return clause.IN{Column: clause.Column{Name: sch.PrimaryFields[0].DBName}, Values: values}, nil
+	}
+	columns := make([]clause.Column, len(sch.PrimaryFields))
+	for i, field := range sch.PrimaryFields {
+		columns[i] = clause.Column{Name: field.DBName}
+	}
+	if len(values) == 0 {
+		return clause.Expr{SQL: "1 <> 1"}, nil
+	}
+	if len(values) == 1 {
+		keyValues := values[0].([]any)
+		exprs := make([]clause.Expression, len(columns))
+		for i, column := range columns {
+			exprs[i] = clause.Eq{Column: column, Value: keyValues[i]}
+		}
+		return clause.And(exprs...), nil
+	}
+	return clause.IN{Column: columns, Values: values}, nil
+}
+
+// primaryKeyValues 解析单个主键值 按主键字段声明顺序返回
+func (b BaseMapper[T]) primaryKeyValues(sch *schema.Schema, id any) ([]any, error) {
+	switch v := id.(type) {
+	case CompositeKey:
+		if len(v) != len(sch.PrimaryFields) {
+			return nil, fmt.Errorf("model %s has %d primary key fields but got %d values", sch.Name, len(sch.PrimaryFields), len(v))
+		}
+		return v, nil
+	case T, *T:
+		rv := reflect.Indirect(reflect.ValueOf(v))
+		keyValues := make([]any, len(sch.PrimaryFields))
+		for i, field := range sch.PrimaryFields {
+			keyValues[i], _ = field.ValueOf(b.context(), rv)
+		}
+		return keyValues, nil
+	}
+	if len(sch.PrimaryFields) > 1 {
+		return nil, fmt.Errorf("model %s has composite primary key, use CompositeKey or model struct instead", sch.Name)
+	}
+	return []any{id}, nil
+}
@@ -0,0 +1,289 @@
+package gormstarter
+
+import (
+	"errors"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrMissingSoftDelete 模型未声明软删除字段
+var ErrMissingSoftDelete = errors.New("model has no soft delete field")
+
+// softDeleteEnabled 与gorm.DeletedAt共用的标记 同一语句只添加一次软删除条件
+const softDeleteEnabled = "soft_delete_enabled"
+
+// softDelete 软删除字段
+type softDelete struct {
+	field *schema.Field
+	flag  bool // 布尔/整数标记 否则为删除时间
+}
+
+// softDelete 获取模型的软删除字段 gorm.DeletedAt 类型字段或通过 gorm:"softDelete" 标签声明的字段 未声明时返回nil
+// 标签声明在布尔/整数类型字段上时为标记删除 0/false 未删除 1/true 已删除 其他类型字段记录删除时间 NULL 为未删除
+func (b BaseMapper[T]) softDelete() (*softDelete, error) {
+	sch, err := b.schema()
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range sch.Fields {
+		if field.FieldType == reflect.TypeOf(gorm.DeletedAt{}) {
+			return &softDelete{field: field}, nil
+		}
+		if _, ok := field.TagSettings["SOFTDELETE"]; ok {
+			switch field.IndirectFieldType.Kind() {
+			case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				return &softDelete{field: field, flag: true}, nil
+			}
+			return &softDelete{field: field}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *softDelete) column() clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: s.field.DBName}
+}
+
+// activeCond 未删除数据的条件
+func (s *softDelete) activeCond() clause.Expression {
+	if s.flag {
+		return clause.Eq{Column: s.column(), Value: s.activeValue()}
+	}
+	return clause.Eq{Column: s.column(), Value: nil}
+}
+
+// deletedCond 已删除数据的条件
+func (s *softDelete) deletedCond() clause.Expression {
+	if s.flag {
+		return clause.Neq{Column: s.column(), Value: s.activeValue()}
+	}
+	return clause.Neq{Column: s.column(), Value: nil}
+}
+
+func (s *softDelete) activeValue() any {
+	if s.field.IndirectFieldType.Kind() == reflect.Bool {
+		return false
+	}
+	return 0
+}
+
+func (s *softDelete) deletedValue(db *gorm.DB) any {
+	if !s.flag {
+		return db.NowFunc()
+	}
+	if s.field.IndirectFieldType.Kind() == reflect.Bool {
+		return true
+	}
+	return 1
+}
+
+// scope 添加未删除数据的条件 write为true时语句没有其他条件则不添加 交由gorm拒绝执行全表更新/删除
+func (s *softDelete) scope(write bool) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		stmt := tx.Statement
+		if _, ok := stmt.Clauses[softDeleteEnabled]; ok || stmt.Unscoped {
+			return tx
+		}
+		if _, ok := stmt.Clauses["WHERE"]; write && !ok {
+			return tx
+		}
+		groupOrConditions(stmt)
+		stmt.AddClause(clause.Where{Exprs: []clause.Expression{s.activeCond()}})
+		stmt.Clauses[softDeleteEnabled] = clause.Clause{}
+		return tx
+	}
+}
+
+// groupOrConditions 与gorm.DeletedAt一致 存在OR条件时将已有条件作为整体 避免追加的条件被OR绕过
+func groupOrConditions(stmt *gorm.Statement) {
+	c, ok := stmt.Clauses["WHERE"]
+	if !ok {
+		return
+	}
+	if where, ok := c.Expression.(clause.Where); ok {
+		for _, expr := range where.Exprs {
+			if orCond, ok := expr.(clause.OrConditions); ok && len(orCond.Exprs) == 1 {
+				where.Exprs = []clause.Expression{clause.And(where.Exprs...)}
+				c.Expression = where
+				stmt.Clauses["WHERE"] = c
+				return
+			}
+		}
+	}
+}
+
+// table 获取限定当前Mapper表名的查询 模型声明软删除字段时自动排除已删除的数据 WithDeleted 时包含已删除的数据
+func (b BaseMapper[T]) table() *gorm.DB {
+	return b.scopedTable(false)
+}
+
+// writeTable 获取限定当前Mapper表名的更新/删除 模型声明软删除字段时不会修改已删除的数据
+func (b BaseMapper[T]) writeTable() *gorm.DB {
+	return b.scopedTable(true)
+}
+
+func (b BaseMapper[T]) scopedTable(write bool) *gorm.DB {
+	db := b.rawDB()
+	if b.unscoped {
+		db = db.Unscoped()
+	}
+	db = b.scopeTenant(db.Table(b.model.TableName()), write)
+	if write {
+		db = b.audit(db)
+	}
+	sd, err := b.softDelete()
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+	if sd == nil {
+		return db
+	}
+	return db.Scopes(sd.scope(write))
+}
+
+// whereEntity 软删除/租户模型通过实体主键显式添加更新条件 避免按实体主键更新时修改已删除或其他租户的数据
+func (b BaseMapper[T]) whereEntity(db *gorm.DB, entity *T) (*gorm.DB, error) {
+	sd, err := b.softDelete()
+	if err != nil {
+		return db, err
+	}
+	t, err := b.tenant()
+	if err != nil || (sd == nil && t == nil) {
+		return db, err
+	}
+	cond, err := b.primaryKeyCond(entity)
+	if err != nil {
+		return nil, err
+	}
+	return db.Where(cond), nil
+}
+
+// delete 删除数据 模型声明软删除字段时仅标记删除
+func (b BaseMapper[T]) delete(db *gorm.DB) (int64, error) {
+	sd, err := b.softDelete()
+	if err != nil {
+		return 0, err
+	}
+	if sd == nil {
+		return checkResult(db.Delete(b.model))
+	}
+	values := map[string]any{sd.field.DBName: sd.deletedValue(db)}
+	if err = b.stampDelete(values); err != nil {
+		return 0, err
+	}
+	return checkResult(b.audit(db, AuditDelete).Updates(values))
+}
+
+// WithDeleted 获取查询时包含已软删除数据的基础Mapper 对 Select* Count* Find* Iterate* 及 Update* 生效
+func (b BaseMapper[T]) WithDeleted() BaseMapper[T] {
+	return BaseMapper[T]{
+		model:      b.model,
+		tx:         b.tx,
+		dataSource: b.dataSource,
+		ctx:        b.ctx,
+		primary:    b.primary,
+		unscoped:   true,
+		noTenant:   b.noTenant,
+	}
+}
+
+// hardTable 获取物理删除/恢复使用的gorm.DB 不附带软删除条件 租户模型仍限定当前租户
+func (b BaseMapper[T]) hardTable() *gorm.DB {
+	return b.audit(b.scopeTenant(b.rawDB().Unscoped().Table(b.model.TableName()), true))
+}
+
+// restore 恢复已软删除的数据 无条件时将拒绝执行
+func (b BaseMapper[T]) restore(db *gorm.DB) (int64, error) {
+	sd, err := b.softDelete()
+	if err != nil {
+		return 0, err
+	}
+	if sd == nil {
+		return 0, ErrMissingSoftDelete
+	}
+	if _, ok := db.Statement.Clauses["WHERE"]; !ok {
+		return 0, gorm.ErrMissingWhereClause
+	}
+	var value any
+	if sd.flag {
+		value = sd.activeValue()
+	}
+	values := map[string]any{sd.field.DBName: value}
+	if err = b.stampMap(values, false); err != nil {
+		return 0, err
+	}
+	return checkResult(db.Where(sd.deletedCond()).Updates(values))
+}
+
+// RestoreById 通过ID恢复已软删除的数据 联合主键时id元素为 CompositeKey 或携带主键值的模型结构体
+func (b BaseMapper[T]) RestoreById(id ...any) (int64, error) {
+	cond, err := b.primaryKeyCond(id...)
+	if err != nil {
+		return 0, err
+	}
+	return b.restore(b.hardTable().Where(cond))
+}
+
+// RestoreByCond 通过条件恢复已软删除的数据 零值字段将被自动忽略
+func (b BaseMapper[T]) RestoreByCond(condition *T) (int64, error) {
+	return b.restore(b.hardTable().Where(condition))
+}
+
+// RestoreByWhere 通过原始SQL恢复已软删除的数据 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
+func (b BaseMapper[T]) RestoreByWhere(rawWhereSql string, args ...any) (int64, error) {
+	return b.restore(b.hardTable().Where(rawWhereSql, args...))
+}
+
+// RestoreByMap 通过Map类型条件恢复已软删除的数据
+func (b BaseMapper[T]) RestoreByMap(condition map[string]any) (int64, error) {
+	return b.restore(b.hardTable().Where(condition))
+}
+
+// HardDeleteById 通过ID物理删除数据 包含已软删除的数据 联合主键时id元素为 CompositeKey 或携带主键值的模型结构体
+func (b BaseMapper[T]) HardDeleteById(id ...any) (int64, error) {
+	cond, err := b.primaryKeyCond(id...)
+	if err != nil {
+		return 0, err
+	}
+	return checkResult(b.hardTable().Where(cond).Delete(b.model))
+}
+
+// HardDeleteByCond 通过条件物理删除数据 零值字段将被自动忽略
+func (b BaseMapper[T]) HardDeleteByCond(condition *T) (int64, error) {
+	return checkResult(b.hardTable().Where(condition).Delete(b.model))
+}
+
+// HardDeleteByWhere 通过原始SQL物理删除数据 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
+func (b BaseMapper[T]) HardDeleteByWhere(rawWhereSql string, args ...any) (int64, error) {
+	return checkResult(b.hardTable().Where(rawWhereSql, args...).Delete(b.model))
+}
+
+// HardDeleteByMap 通过Map类型条件物理删除数据
+func (b BaseMapper[T]) HardDeleteByMap(condition map[string]any) (int64, error) {
+	return checkResult(b.hardTable().Where(condition).Delete(b.model))
+}
+
+// PurgeDeleted 物理删除所有已软删除的数据 记录删除时间的软删除字段可通过 before 仅清理该时间之前删除的数据
+func (b BaseMapper[T]) PurgeDeleted(before ...time.Time) (int64, error) {
+	sd, err := b.softDelete()
+	if err != nil {
+		return 0, err
+	}
+	if sd == nil {
+		return 0, ErrMissingSoftDelete
+	}
+	db := b.hardTable().Where(sd.deletedCond())
+	if len(before) > 0 {
+		if sd.flag {
+			return 0, errors.New("soft delete flag " + sd.field.DBName + " does not record deleted time")
+		}
+		db = db.Where(clause.Lt{Column: sd.column(), Value: before[0]})
+	}
+	return checkResult(db.Delete(b.model))
+}
@@ -0,0 +1,181 @@
+package gormstarter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrMissingTenant 租户模型未从上下文中获取到当前租户
+var ErrMissingTenant = errors.New("tenant not found in context")
+
+// ErrTenantConflict 主键已被其他租户的数据使用
+var ErrTenantConflict = errors.New("primary key belongs to another tenant")
+
+// tenantEnabled 同一语句只添加一次租户条件
+const tenantEnabled = "gormstarter:tenant_enabled"
+
+// IBaseModelWithTenant 多租户模型 通过 BaseMapper 查询/更新/删除时自动限定当前租户 新增时自动填充租户字段
+// 当前租户由 GormConfig.TenantResolver 从上下文中获取
+type IBaseModelWithTenant interface {
+	TableName() string
+	TenantColumn() string // 租户字段 数据库字段/结构体字段
+}
+
+// 数据源对应的租户获取函数 key: 数据源名称
+var tenantResolvers = make(map[string]func(ctx context.Context) any)
+
+type tenantContextKey struct{}
+
+// ContextWithoutTenant 获取不限定租户的上下文 使用该上下文的 BaseMapper 将不再限定租户 用于跨租户的管理任务
+func ContextWithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, true)
+}
+
+// tenant 租户字段及当前租户
+type tenant struct {
+	field *schema.Field
+	value any
+}
+
+// tenant 获取模型的租户字段及当前租户 非租户模型或已跳过租户限定时返回nil 未获取到当前租户时返回 ErrMissingTenant
+func (b BaseMapper[T]) tenant() (*tenant, error) {
+	model, ok := any(b.model).(IBaseModelWithTenant)
+	if !ok || b.noTenant {
+		return nil, nil
+	}
+	ctx := b.context()
+	if skip, _ := ctx.Value(tenantContextKey{}).(bool); skip {
+		return nil, nil
+	}
+	sch, err := b.schema()
+	if err != nil {
+		return nil, err
+	}
+	column, err := lookupColumn(sch, model.TenantColumn())
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if resolver := tenantResolvers[b.DataSource()]; resolver != nil {
+		value = resolver(ctx)
+	}
+	if value == nil {
+		return nil, ErrMissingTenant
+	}
+	return &tenant{field: sch.LookUpField(column), value: value}, nil
+}
+
+func (t *tenant) cond() clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: t.field.DBName}, Value: t.value}
+}
+
+// scope 添加当前租户条件 write为true时语句没有其他条件则拒绝执行 避免通过实体主键更新其他租户的数据
+func (t *tenant) scope(write bool) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		stmt := tx.Statement
+		if _, ok := stmt.Clauses[tenantEnabled]; ok {
+			return tx
+		}
+		if _, ok := stmt.Clauses["WHERE"]; write && !ok {
+			_ = tx.AddError(gorm.ErrMissingWhereClause)
+			return tx
+		}
+		groupOrConditions(stmt)
+		stmt.AddClause(clause.Where{Exprs: []clause.Expression{t.cond()}})
+		stmt.Clauses[tenantEnabled] = clause.Clause{}
+		return tx
+	}
+}
+
+// scopeTenant 为租户模型添加当前租户条件
+func (b BaseMapper[T]) scopeTenant(db *gorm.DB, write bool) *gorm.DB {
+	t, err := b.tenant()
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+	if t == nil {
+		return db
+	}
+	return db.Scopes(t.scope(write))
+}
+
+// stampTenant 将实体的租户字段设置为当前租户
+func (b BaseMapper[T]) stampTenant(entities ...*T) error {
+	t, err := b.tenant()
+	if err != nil || t == nil {
+		return err
+	}
+	for _, entity := range entities {
+		if err = t.field.Set(b.context(), reflect.ValueOf(entity).Elem(), t.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stampTenantMap 将map中的租户字段设置为当前租户 create 为false时仅覆盖map中已指定的租户字段
+func (b BaseMapper[T]) stampTenantMap(values map[string]any, create bool) error {
+	t, err := b.tenant()
+	if err != nil || t == nil {
+		return err
+	}
+	_, ok := values[t.field.Name]
+	delete(values, t.field.Name)
+	if _, exists := values[t.field.DBName]; create || ok || exists {
+		values[t.field.DBName] = t.value
+	}
+	return nil
+}
+
+// saveTenant 租户模型在同一事务中确认主键所属的租户 属于当前租户时更新 不存在时新增 属于其他租户时返回 ErrTenantConflict
+func (b BaseMapper[T]) saveTenant(entity *T, omits []string, t *tenant) (int64, error) {
+	var rows int64
+	err := b.Transaction(func(ctx context.Context, mapper BaseMapper[T]) error {
+		cond, err := mapper.primaryKeyCond(entity)
+		if err != nil {
+			return err
+		}
+		var existing []*T
+		if err = mapper.rawDB().Unscoped().Table(b.model.TableName()).Where(cond).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		db := mapper.audit(mapper.rawDB())
+		if len(omits) > 0 {
+			db = db.Omit(omits...)
+		}
+		if len(existing) == 0 {
+			rows, err = checkResult(db.Create(entity))
+			return err
+		}
+		owner, _ := t.field.ValueOf(ctx, reflect.ValueOf(existing[0]).Elem())
+		if fmt.Sprint(owner) != fmt.Sprint(t.value) {
+			return ErrTenantConflict
+		}
+		rows, err = checkResult(db.Where(t.cond()).Select("*").Save(entity))
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rows, nil
+}
+
+// WithoutTenant 获取不限定租户的基础Mapper 查询/更新/删除不再附加租户条件 新增时不填充租户字段 用于跨租户的管理任务
+func (b BaseMapper[T]) WithoutTenant() BaseMapper[T] {
+	return BaseMapper[T]{
+		model:      b.model,
+		tx:         b.tx,
+		dataSource: b.dataSource,
+		ctx:        b.ctx,
+		primary:    b.primary,
+		unscoped:   b.unscoped,
+		noTenant:   true,
+	}
+}
@@ -0,0 +1,108 @@
+package gormstarter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+)
+
+const (
+	TLSModeDisable    TLSMode = "disable"     // 不使用加密连接
+	TLSModeRequire    TLSMode = "require"     // 使用加密连接 不校验服务端证书
+	TLSModeVerifyCA   TLSMode = "verify-ca"   // 使用加密连接 校验服务端证书由受信任的CA签发
+	TLSModeVerifyFull TLSMode = "verify-full" // 使用加密连接 校验服务端证书由受信任的CA签发且与服务端主机名匹配
+)
+
+type TLSMode string
+
+// TLSConfig 数据库加密连接配置 证书同时指定文件路径及PEM内容时优先使用PEM内容
+type TLSConfig struct {
+	Mode TLSMode // 不指定时默认为 disable
+
+	CAFile string // 受信任的CA证书文件路径 不指定时使用系统CA
+	CAPem  []byte // 受信任的CA证书PEM内容
+
+	CertFile string // 客户端证书文件路径
+	CertPem  []byte // 客户端证书PEM内容
+	KeyFile  string // 客户端私钥文件路径
+	KeyPem   []byte // 客户端私钥PEM内容
+
+	ServerName string // 校验服务端证书使用的主机名 不指定时默认为Host
+}
+
+func (t *TLSConfig) enabled() bool {
+	return t != nil && t.Mode != "" && t.Mode != TLSModeDisable
+}
+
+// buildTLSConfig 通过配置创建 tls.Config 未启用加密连接时返回nil
+func buildTLSConfig(config *GormConfig) (*tls.Config, error) {
+	t := config.TLS
+	if !t.enabled() {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{ServerName: t.ServerName}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = config.Host
+	}
+
+	caPem, err := pemContent(t.CAPem, t.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(caPem) > 0 {
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caPem) {
+			return nil, errors.New("invalid tls ca certificate")
+		}
+	}
+
+	certPem, err := pemContent(t.CertPem, t.CertFile)
+	if err != nil {
+		return nil, err
+	}
+	keyPem, err := pemContent(t.KeyPem, t.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(certPem) > 0 || len(keyPem) > 0 {
+		cert, err := tls.X509KeyPair(certPem, keyPem)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	switch t.Mode {
+	case TLSModeRequire:
+		tlsConfig.InsecureSkipVerify = true
+	case TLSModeVerifyCA:
+		// 仅校验证书链 不校验主机名
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("tls: server did not provide a certificate")
+			}
+			opts := x509.VerifyOptions{
+				Roots:         tlsConfig.RootCAs,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, cert := range state.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := state.PeerCertificates[0].Verify(opts)
+			return err
+		}
+	case TLSModeVerifyFull:
+	default:
+		return nil, errors.New("not supported tls mode " + string(t.Mode))
+	}
+	return tlsConfig, nil
+}
+
+func pemContent(pem []byte, file string) ([]byte, error) {
+	if len(pem) > 0 || file == "" {
+		return pem, nil
+	}
+	return os.ReadFile(file)
+}
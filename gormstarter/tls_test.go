@@ -0,0 +1,274 @@
+package gormstarter
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCert 测试使用的证书及私钥
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPem []byte
+	keyPem  []byte
+}
+
+// newTestCert 生成证书 parent为nil时生成自签名CA证书
+func newTestCert(t *testing.T, parent *testCert, dnsNames ...string) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "gormstarter test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     dnsNames,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPem:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+	}
+}
+
+func writeTestFile(t *testing.T, name string, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// handshake 使用客户端配置与持有服务端证书的服务端握手
+func handshake(t *testing.T, client *tls.Config, server *testCert) error {
+	t.Helper()
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{{
+		Certificate: [][]byte{server.cert.Raw},
+		PrivateKey:  server.key,
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_ = conn.(*tls.Conn).Handshake()
+		_ = conn.Close()
+	}()
+	conn, err := net.DialTimeout("tcp", listener.Addr().String(), 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return tls.Client(conn, client).Handshake()
+}
+
+func TestBuildTLSConfigMode(t *testing.T) {
+	for _, mode := range []TLSMode{"", TLSModeDisable} {
+		tlsConfig, err := buildTLSConfig(&GormConfig{TLS: &TLSConfig{Mode: mode}})
+		if err != nil || tlsConfig != nil {
+			t.Fatal(mode, tlsConfig, err)
+		}
+	}
+	if tlsConfig, err := buildTLSConfig(&GormConfig{}); err != nil || tlsConfig != nil {
+		t.Fatal(tlsConfig, err)
+	}
+
+	tlsConfig, err := buildTLSConfig(&GormConfig{Host: "db.internal", TLS: &TLSConfig{Mode: TLSModeRequire}})
+	if err != nil || !tlsConfig.InsecureSkipVerify || tlsConfig.VerifyConnection != nil || tlsConfig.ServerName != "db.internal" {
+		t.Fatal(tlsConfig, err)
+	}
+	tlsConfig, err = buildTLSConfig(&GormConfig{Host: "db.internal", TLS: &TLSConfig{Mode: TLSModeVerifyCA}})
+	if err != nil || !tlsConfig.InsecureSkipVerify || tlsConfig.VerifyConnection == nil {
+		t.Fatal(tlsConfig, err)
+	}
+	tlsConfig, err = buildTLSConfig(&GormConfig{Host: "db.internal", TLS: &TLSConfig{Mode: TLSModeVerifyFull, ServerName: "primary.db"}})
+	if err != nil || tlsConfig.InsecureSkipVerify || tlsConfig.VerifyConnection != nil || tlsConfig.ServerName != "primary.db" {
+		t.Fatal(tlsConfig, err)
+	}
+	if _, err = buildTLSConfig(&GormConfig{TLS: &TLSConfig{Mode: "prefer"}}); err == nil {
+		t.Fatal("expect not supported tls mode error")
+	}
+}
+
+func TestBuildTLSConfigPem(t *testing.T) {
+	ca := newTestCert(t, nil)
+	client := newTestCert(t, ca)
+	other := newTestCert(t, nil)
+
+	// 同时指定时优先使用PEM内容 文件不存在也不会读取
+	tlsConfig, err := buildTLSConfig(&GormConfig{TLS: &TLSConfig{
+		Mode:     TLSModeVerifyFull,
+		CAPem:    ca.certPem,
+		CAFile:   filepath.Join(t.TempDir(), "missing.pem"),
+		CertPem:  client.certPem,
+		CertFile: writeTestFile(t, "cert.pem", other.certPem),
+		KeyPem:   client.keyPem,
+		KeyFile:  writeTestFile(t, "key.pem", other.keyPem),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tlsConfig.RootCAs.Equal(poolOf(ca)) {
+		t.Fatal("expect ca from pem")
+	}
+	if len(tlsConfig.Certificates) != 1 || !client.cert.Equal(leafOf(t, tlsConfig.Certificates[0])) {
+		t.Fatal("expect client certificate from pem")
+	}
+
+	// 未指定PEM内容时读取文件
+	tlsConfig, err = buildTLSConfig(&GormConfig{TLS: &TLSConfig{
+		Mode:     TLSModeVerifyFull,
+		CAFile:   writeTestFile(t, "ca.pem", other.certPem),
+		CertFile: writeTestFile(t, "cert.pem", client.certPem),
+		KeyFile:  writeTestFile(t, "key.pem", client.keyPem),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tlsConfig.RootCAs.Equal(poolOf(other)) || !client.cert.Equal(leafOf(t, tlsConfig.Certificates[0])) {
+		t.Fatal("expect certificates from file")
+	}
+
+	if _, err = buildTLSConfig(&GormConfig{TLS: &TLSConfig{Mode: TLSModeVerifyFull, CAFile: filepath.Join(t.TempDir(), "missing.pem")}}); err == nil {
+		t.Fatal("expect missing ca file error")
+	}
+	if _, err = buildTLSConfig(&GormConfig{TLS: &TLSConfig{Mode: TLSModeVerifyFull, CAPem: []byte("invalid")}}); err == nil {
+		t.Fatal("expect invalid ca error")
+	}
+	if _, err = buildTLSConfig(&GormConfig{TLS: &TLSConfig{Mode: TLSModeVerifyFull, CertPem: client.certPem}}); err == nil {
+		t.Fatal("expect missing key error")
+	}
+}
+
+func TestBuildTLSConfigVerifyCA(t *testing.T) {
+	ca := newTestCert(t, nil)
+	server := newTestCert(t, ca, "db.internal")
+	unknown := newTestCert(t, nil)
+	untrusted := newTestCert(t, unknown, "db.internal")
+
+	// verify-ca 不校验主机名
+	verifyCA, err := buildTLSConfig(&GormConfig{Host: "127.0.0.1", TLS: &TLSConfig{Mode: TLSModeVerifyCA, CAPem: ca.certPem}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = handshake(t, verifyCA, server); err != nil {
+		t.Fatal(err)
+	}
+	if err = handshake(t, verifyCA, untrusted); err == nil {
+		t.Fatal("expect unknown authority error")
+	}
+
+	// verify-full 校验主机名
+	verifyFull, err := buildTLSConfig(&GormConfig{Host: "127.0.0.1", TLS: &TLSConfig{Mode: TLSModeVerifyFull, CAPem: ca.certPem}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = handshake(t, verifyFull, server); err == nil {
+		t.Fatal("expect hostname mismatch error")
+	}
+	verifyFull.ServerName = "db.internal"
+	if err = handshake(t, verifyFull, server); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostgresSSLMode(t *testing.T) {
+	ca := newTestCert(t, nil)
+	cases := []struct {
+		config GormConfig
+		mode   string
+	}{
+		{GormConfig{Host: "db", Port: 5432}, "sslmode=disable"},
+		{GormConfig{Host: "db", Port: 5432, PostgresEnableSSl: true}, "sslmode=require"},
+		{GormConfig{Host: "db", Port: 5432, dsnParams: url.Values{"sslmode": {"prefer"}}}, "sslmode=prefer"},
+		{GormConfig{Host: "db", Port: 5432, PostgresEnableSSl: true, TLS: &TLSConfig{Mode: TLSModeVerifyCA, CAPem: ca.certPem}}, "sslmode=verify-ca"},
+		{GormConfig{Host: "db", Port: 5432, dsnParams: url.Values{"sslmode": {"disable"}}, TLS: &TLSConfig{Mode: TLSModeVerifyFull, CAPem: ca.certPem}}, "sslmode=verify-full"},
+	}
+	for _, c := range cases {
+		tlsConfig, err := buildTLSConfig(&c.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dsn := postgresConfigDSN(&c.config, tlsConfig)
+		if !strings.Contains(dsn, c.mode) {
+			t.Fatalf("%s not in %s", c.mode, dsn)
+		}
+		if tlsConfig == nil {
+			continue
+		}
+		// 驱动通过sslmode生成的配置被替换为自定义的TLS配置
+		connConfig, err := postgresConnConfig(dsn, tlsConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if connConfig.TLSConfig != tlsConfig || len(connConfig.Fallbacks) != 0 {
+			t.Fatal(connConfig.TLSConfig, connConfig.Fallbacks)
+		}
+	}
+}
+
+func poolOf(certs ...*testCert) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert.cert)
+	}
+	return pool
+}
+
+func leafOf(t *testing.T, cert tls.Certificate) *x509.Certificate {
+	t.Helper()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf
+}
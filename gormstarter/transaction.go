@@ -0,0 +1,171 @@
+package gormstarter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// txContextKey 上下文中保存事务的key 按数据源区分
+type txContextKey struct {
+	dataSource string
+}
+
+const (
+	PropagationRequired    Propagation = iota // 存在事务时加入 否则开启新事务
+	PropagationRequiresNew                    // 总是开启独立的新事务 与已有事务互不影响
+	PropagationNested                         // 存在事务时通过SAVEPOINT开启嵌套事务 失败仅回滚到保存点 否则开启新事务
+	PropagationSupports                       // 存在事务时加入 否则以非事务方式执行
+	PropagationNever                          // 以非事务方式执行 存在事务时返回 ErrExistingTransaction
+)
+
+// ErrExistingTransaction 传播行为为 PropagationNever 时上下文中已存在事务
+var ErrExistingTransaction = errors.New("existing transaction found for propagation never")
+
+// Propagation 事务传播行为
+type Propagation int
+
+// TxDefinition 事务定义
+type TxDefinition struct {
+	DataSource  string         // 数据源名称 不指定时使用默认数据源
+	Propagation Propagation    // 传播行为 默认为 PropagationRequired
+	Options     *sql.TxOptions // 开启新事务时使用的事务选项
+}
+
+// Transaction 在默认数据源上执行声明式事务 fn返回nil时提交 返回error或panic时回滚
+// 事务将保存在fn的上下文中 使用该上下文的BaseMapper (通过WithContext) 将自动加入此事务
+// 上下文中已存在该数据源的事务时 fn将直接加入已有事务
+func Transaction(ctx context.Context, fn func(ctx context.Context) error, opts ...*sql.TxOptions) error {
+	return TransactionWithDataSource(ctx, defaultDataSource, fn, opts...)
+}
+
+// TransactionWithDataSource 在指定数据源上执行声明式事务 规则同 Transaction
+func TransactionWithDataSource(ctx context.Context, dataSource string, fn func(ctx context.Context) error, opts ...*sql.TxOptions) error {
+	definition := TxDefinition{DataSource: dataSource}
+	if len(opts) > 0 {
+		definition.Options = opts[0]
+	}
+	return TransactionWithDefinition(ctx, definition, fn)
+}
+
+// TransactionWithPropagation 在默认数据源上按指定传播行为执行声明式事务
+func TransactionWithPropagation(ctx context.Context, propagation Propagation, fn func(ctx context.Context) error) error {
+	return TransactionWithDefinition(ctx, TxDefinition{Propagation: propagation}, fn)
+}
+
+// TransactionWithDefinition 按事务定义执行声明式事务
+func TransactionWithDefinition(ctx context.Context, definition TxDefinition, fn func(ctx context.Context) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	dataSource := definition.DataSource
+	if dataSource == "" {
+		dataSource = defaultDataSource
+	}
+	existing := TxFromContext(ctx, dataSource)
+	switch definition.Propagation {
+	case PropagationRequired:
+		if existing != nil {
+			return fn(ctx)
+		}
+	case PropagationRequiresNew:
+	case PropagationNested:
+		if existing != nil {
+			// 已处于事务中时 gorm将使用 SAVEPOINT / ROLLBACK TO SAVEPOINT 实现嵌套事务
+			return existing.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				return fn(ContextWithTx(ctx, tx, dataSource))
+			})
+		}
+	case PropagationSupports:
+		return fn(ctx)
+	case PropagationNever:
+		if existing != nil {
+			return ErrExistingTransaction
+		}
+		return fn(ctx)
+	default:
+		return errors.New("not supported transaction propagation " + strconv.Itoa(int(definition.Propagation)))
+	}
+	db, ok := gormDBs[dataSource]
+	if !ok {
+		return fmt.Errorf("%w %s", ErrUnknownDataSource, dataSource)
+	}
+	var opts []*sql.TxOptions
+	if definition.Options != nil {
+		opts = append(opts, definition.Options)
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(ContextWithTx(ctx, tx, dataSource))
+	}, opts...)
+}
+
+// joinedTx 加入已有事务的连接 提交由事务的发起方负责 回滚将回滚整个事务
+type joinedTx struct {
+	gorm.ConnPool
+	committer gorm.TxCommitter
+}
+
+func (j *joinedTx) Commit() error {
+	return nil
+}
+
+func (j *joinedTx) Rollback() error {
+	return j.committer.Rollback()
+}
+
+// joinTx 获取加入已有事务的gorm.DB 不是事务时返回nil
+func joinTx(ctx context.Context, existing *gorm.DB) *gorm.DB {
+	committer, ok := existing.Statement.ConnPool.(gorm.TxCommitter)
+	if !ok || committer == nil {
+		return nil
+	}
+	tx := existing.Session(&gorm.Session{Context: ctx})
+	tx.Statement.ConnPool = &joinedTx{ConnPool: existing.Statement.ConnPool, committer: committer}
+	return tx
+}
+
+// ContextWithTx 将已有事务保存到上下文中 使用该上下文的BaseMapper及 Transaction 将加入此事务 不指定数据源时使用默认数据源
+// 用于将 GetBaseMapperWithTx / NewBaseMapperWithTx 开启的事务传递给基于上下文的调用
+func ContextWithTx(ctx context.Context, tx *gorm.DB, dataSource ...string) context.Context {
+	name := defaultDataSource
+	if len(dataSource) > 0 {
+		name = dataSource[0]
+	}
+	return context.WithValue(ctx, txContextKey{dataSource: name}, tx)
+}
+
+// TxFromContext 获取上下文中指定数据源的事务 不指定数据源时使用默认数据源 不存在时返回nil
+func TxFromContext(ctx context.Context, dataSource ...string) *gorm.DB {
+	if ctx == nil {
+		return nil
+	}
+	name := defaultDataSource
+	if len(dataSource) > 0 {
+		name = dataSource[0]
+	}
+	tx, _ := ctx.Value(txContextKey{dataSource: name}).(*gorm.DB)
+	return tx
+}
+
+// Transaction 按传播行为在事务中执行fn 不指定时为 PropagationRequired fn返回nil时提交 返回error或panic时回滚
+// Mapper已持有事务 (GetBaseMapperWithTx / NewBaseMapperWithTx) 或上下文中已存在事务时按传播行为加入、挂起或嵌套该事务
+// fn中的mapper使用当前事务 ctx中保存了当前事务 可传递给其他Mapper (通过WithContext) 加入同一事务
+func (b BaseMapper[T]) Transaction(fn func(ctx context.Context, mapper BaseMapper[T]) error, propagation ...Propagation) error {
+	definition := TxDefinition{DataSource: b.DataSource()}
+	if len(propagation) > 0 {
+		definition.Propagation = propagation[0]
+	}
+	ctx := b.context()
+	if b.tx != nil {
+		ctx = ContextWithTx(ctx, b.tx, definition.DataSource)
+	}
+	return TransactionWithDefinition(ctx, definition, func(ctx context.Context) error {
+		mapper := b.WithContext(ctx)
+		mapper.tx = nil
+		return fn(ctx, mapper)
+	})
+}
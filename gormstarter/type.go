@@ -1,9 +1,11 @@
 package gormstarter
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
+	"iter"
 	"time"
 
 	"github.com/acexy/golang-toolkit/util/json"
@@ -13,6 +15,7 @@ import (
 const (
 	DBTypeMySQL    DBType = "mysql"
 	DBTypePostgres DBType = "postgres"
+	DBTypeSQLite   DBType = "sqlite"
 )
 
 // Timestamp 时间戳处理 接收数据库的时间类型
@@ -20,19 +23,34 @@ type Timestamp json.Timestamp
 
 type DBType string
 
+// CompositeKey 联合主键值 按模型主键字段声明顺序依次排列
+type CompositeKey []any
+
 type IBaseModel interface {
 	TableName() string
 }
 
 // IBaseModelWithDBType 当gorm管理多个不同数据库类型时，需要实现此接口 以便指定该数据库类型 （初始化加载的第一个数据库类型不需要指定）
+// 同一数据库类型存在多个数据源时，将使用该类型最先加载的数据源
 type IBaseModelWithDBType interface {
 	TableName() string
 	DBType() DBType
 }
 
+// IBaseModelWithDataSource 当gorm管理多个数据源时，可实现此接口 以便通过数据源名称指定该模型所使用的数据源 优先级高于 IBaseModelWithDBType
+type IBaseModelWithDataSource interface {
+	TableName() string
+	DataSource() string
+}
+
 type BaseMapper[M IBaseModel] struct {
-	model M
-	tx    *gorm.DB
+	model      M
+	tx         *gorm.DB
+	dataSource string
+	ctx        context.Context
+	primary    bool
+	unscoped   bool
+	noTenant   bool
 }
 
 func (t *Timestamp) Scan(value interface{}) error {
@@ -69,6 +87,9 @@ func (t *Timestamp) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// IBaseMapper 基础Mapper
+// orderBy 排序 格式为 字段 [ASC|DESC] 多个排序以逗号分隔 specifyColumns/excludeColumns 等字段参数为 Column 描述
+// 字段将根据模型结构校验 字段不存在时返回 ErrUnknownColumn
 type IBaseMapper[M BaseMapper[T], T IBaseModel] interface {
 
 	// GormWithTableName Mapper对应的原生Gorm操作能力 获取到的原始gorm.DB已经限定当前Mapper所对应的表名
@@ -80,30 +101,52 @@ type IBaseMapper[M BaseMapper[T], T IBaseModel] interface {
 	// GetBaseMapperWithTx 获取携带指定事务的基础Mapper
 	GetBaseMapperWithTx(tx *gorm.DB) BaseMapper[T]
 
-	// NewBaseMapperWithTx 创建一个全新事务的基础Mapper
+	// NewBaseMapperWithTx 获取事务中的基础Mapper 传播行为为 PropagationRequired
+	// Mapper或上下文中已存在事务时加入该事务 此时 Commit 由事务的发起方负责 Rollback 将回滚整个事务 否则开启新事务
 	NewBaseMapperWithTx(opts ...*sql.TxOptions) BaseMapper[T]
 
-	// SelectById 通过主键查询数据
+	// Transaction 按传播行为在事务中执行fn 不指定时为 PropagationRequired Mapper或上下文中已存在事务时按传播行为加入、挂起或嵌套该事务
+	Transaction(fn func(ctx context.Context, mapper BaseMapper[T]) error, propagation ...Propagation) error
+
+	// GetBaseMapperWithDataSource 获取使用指定数据源的基础Mapper 优先级高于模型自身声明的数据源
+	GetBaseMapperWithDataSource(dataSource string) BaseMapper[T]
+
+	// DataSource 获取当前Mapper所使用的数据源名称
+	DataSource() string
+
+	// WithContext 获取携带指定上下文的基础Mapper 该Mapper执行的所有操作都将使用此上下文 用于传递取消信号、超时及链路信息
+	WithContext(ctx context.Context) BaseMapper[T]
+
+	// WithPrimary 获取强制使用主库的基础Mapper 配置读写分离时查询操作也将路由到主库 用于写后立即读的场景
+	WithPrimary() BaseMapper[T]
+
+	// WithDeleted 获取查询时包含已软删除数据的基础Mapper 对 Select* Count* Find* Iterate* 及 Update* 生效
+	WithDeleted() BaseMapper[T]
+
+	// WithoutTenant 获取不限定租户的基础Mapper 查询/更新/删除不再附加租户条件 新增时不填充租户字段 用于跨租户的管理任务
+	WithoutTenant() BaseMapper[T]
+
+	// SelectById 通过主键查询数据 联合主键时id为 CompositeKey 或携带主键值的模型结构体
 	SelectById(id any, result *T) (int64, error)
 
-	// SelectByIds 通过主键查询数据
+	// SelectByIds 通过主键查询数据 联合主键时id元素为 CompositeKey 或携带主键值的模型结构体
 	SelectByIds(id []any, result *[]*T) (int64, error)
 
 	// SelectOneByCond 通过条件查询 查询条件零值字段将被自动忽略
 	// specifyColumns 指定只需要查询的数据库字段
-	SelectOneByCond(condition, result *T, specifyColumns ...string) (int64, error)
+	SelectOneByCond(condition, result *T, specifyColumns ...Column[T]) (int64, error)
 
 	// SelectByCond 通过条件查询 查询条件零值字段将被自动忽略
 	// specifyColumns 指定只需要查询的数据库字段
-	SelectByCond(condition *T, orderBy string, result *[]*T, specifyColumns ...string) (int64, error)
+	SelectByCond(condition *T, orderBy string, result *[]*T, specifyColumns ...Column[T]) (int64, error)
 
 	// SelectOneByMap 通过指定字段与值查询数据 解决查询条件零值问题
 	// specifyColumns 指定只需要查询的数据库字段
-	SelectOneByMap(condition map[string]any, result *T, specifyColumns ...string) (int64, error)
+	SelectOneByMap(condition map[string]any, result *T, specifyColumns ...Column[T]) (int64, error)
 
 	// SelectByMap 通过指定字段与值查询数据 解决零值条件问题
 	// specifyColumns 指定只需要查询的数据库字段
-	SelectByMap(condition map[string]any, orderBy string, result *[]*T, specifyColumns ...string) (int64, error)
+	SelectByMap(condition map[string]any, orderBy string, result *[]*T, specifyColumns ...Column[T]) (int64, error)
 
 	// SelectOneByWhere 通过原始Where SQL查询 只需要输入SQL语句和参数 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
 	SelectOneByWhere(rawWhereSql string, result *T, args ...any) (int64, error)
@@ -131,26 +174,34 @@ type IBaseMapper[M BaseMapper[T], T IBaseModel] interface {
 
 	// SelectPageByCond 通过条件分页查询 零值字段将被自动忽略
 	// specifyColumns 指定只需要查询的数据库字段 pageNumber 页码 1开始
-	SelectPageByCond(condition *T, orderBy string, pageNumber, pageSize int, result *[]*T, specifyColumns ...string) (total int64, err error)
+	SelectPageByCond(condition *T, orderBy string, pageNumber, pageSize int, result *[]*T, specifyColumns ...Column[T]) (total int64, err error)
 
 	// SelectPageByMap 通过指定字段与值查询数据分页查询 解决零值条件问题
 	// specifyColumns 指定只需要查询的数据库字段 pageNumber 页码 1开始
-	SelectPageByMap(condition map[string]any, orderBy string, pageNumber, pageSize int, result *[]*T, specifyColumns ...string) (total int64, err error)
+	SelectPageByMap(condition map[string]any, orderBy string, pageNumber, pageSize int, result *[]*T, specifyColumns ...Column[T]) (total int64, err error)
 
 	// SelectPageByWhere 通过原始SQL分页查询 rawWhereSql 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
 	// specifyColumns 指定只需要查询的数据库字段 pageNumber 页码 1开始
-	SelectPageByWhere(rawWhereSql, orderBy string, pageNumber, pageSize int, result *[]*T, args []any, specifyColumns ...string) (total int64, err error)
+	SelectPageByWhere(rawWhereSql, orderBy string, pageNumber, pageSize int, result *[]*T, args []any, specifyColumns ...Column[T]) (total int64, err error)
 
 	// SelectPageByGorm 通过原始Gorm分页查询
 	SelectPageByGorm(countRawDb func(*gorm.DB), pageRawDb func(*gorm.DB), result *[]*T) (total int64, err error)
 
 	// Insert 保存数据 零值也将参与保存
 	//	exclude 手动指定需要排除的字段名称 数据库字段/结构体字段名称
-	Insert(entity *T, excludeColumns ...string) (int64, error)
+	Insert(entity *T, excludeColumns ...Column[T]) (int64, error)
 
 	// InsertBatch 批量新增 零值也将参与保存
 	//	exclude 手动指定需要排除的字段名称 数据库字段/结构体字段
-	InsertBatch(entities *[]*T, excludeColumns ...string) (int64, error)
+	InsertBatch(entities *[]*T, excludeColumns ...Column[T]) (int64, error)
+
+	// InsertInBatches 分批新增 零值也将参与保存 每批最多batchSize条 batchSize <= 0 时使用 GormConfig.CreateBatchSize 均未指定时不分批
+	// 返回每批的影响行数 某一批失败时已成功的批次不会回滚 需要整体回滚时使用 InsertInBatchesWithTx 或在 Transaction 中调用
+	//	exclude 手动指定需要排除的字段名称 数据库字段/结构体字段
+	InsertInBatches(entities *[]*T, batchSize int, excludeColumns ...Column[T]) ([]int64, error)
+
+	// InsertInBatchesWithTx 在同一事务中分批新增 任一批失败时回滚全部批次 当前Mapper已处于事务中时使用该事务 其他规则同 InsertInBatches
+	InsertInBatchesWithTx(entities *[]*T, batchSize int, excludeColumns ...Column[T]) ([]int64, error)
 
 	// InsertWithoutZeroField 保存数据 零值将不会参与保存
 	InsertWithoutZeroField(entity *T) (int64, error)
@@ -161,25 +212,35 @@ type IBaseMapper[M BaseMapper[T], T IBaseModel] interface {
 	// InsertOrUpdateByPrimaryKey 保存/更新数据 零值也将参与保存
 	// exclude 手动指定需要排除的字段名称 数据库字段/结构体字段 (如果触发的是update 创建时间可能会被错误的修改，可以通过excludeColumns来指定排除创建时间字段)
 	// 仅根据主键冲突默认支持update 更多操作需要参阅 https://gorm.io/zh_CN/docs/create.html#upsert
-	InsertOrUpdateByPrimaryKey(entity *T, excludeColumns ...string) (int64, error)
+	// 租户模型的主键已被其他租户的数据使用时返回 ErrTenantConflict
+	InsertOrUpdateByPrimaryKey(entity *T, excludeColumns ...Column[T]) (int64, error)
+
+	// InsertOnConflict 新增数据 冲突时按 onConflict 更新指定字段或不做处理 零值也将参与保存
+	// 与 InsertOrUpdateByPrimaryKey 不同 默认不会覆盖创建时间 且支持唯一索引冲突
+	InsertOnConflict(entity *T, onConflict OnConflict[T]) (int64, error)
 
-	// UpdateById 通过ID更新含零值字段
+	// InsertBatchOnConflict 批量新增数据 冲突时按 onConflict 更新指定字段或不做处理 零值也将参与保存
+	InsertBatchOnConflict(entities *[]*T, onConflict OnConflict[T]) (int64, error)
+
+	// UpdateById 通过ID更新含零值字段 联合主键时将使用实体中的全部主键值作为条件
 	// updateColumns 手动指定需要更新的列
-	UpdateById(updated *T, updateColumns ...string) (int64, error)
+	// 模型通过 gorm:"version" 标签声明版本号字段时启用乐观锁 以实体中的版本号作为条件并加1 未更新任何数据时返回 ErrOptimisticLock
+	UpdateById(updated *T, updateColumns ...Column[T]) (int64, error)
 
-	// UpdateByIdWithoutZeroField 通过ID更新非零值字段
+	// UpdateByIdWithoutZeroField 通过ID更新非零值字段 乐观锁规则同 UpdateById
 	// allowZeroFiledColumns 额外指定需要更新零值字段
-	UpdateByIdWithoutZeroField(updated *T, allowZeroFiledColumns ...string) (int64, error)
+	UpdateByIdWithoutZeroField(updated *T, allowZeroFiledColumns ...Column[T]) (int64, error)
 
-	// UpdateByIdUseMap 通过ID更新所有map中指定的列和值
+	// UpdateByIdUseMap 通过ID更新所有map中指定的列和值 联合主键时id为 CompositeKey 或携带主键值的模型结构体
+	// 启用乐观锁时以map中的版本号作为条件并加1 map中未指定版本号时仅将版本号加1
 	UpdateByIdUseMap(updated map[string]any, id any) (int64, error)
 
 	// UpdateByCond 通过条件更新 条件：零值将自动忽略，更新：零值字段将被自动忽略
 	// updateColumns 需要指定更新的数据库字段 更新指定字段(支持零值字段)
-	UpdateByCond(updated, condition *T, updateColumns ...string) (int64, error)
+	UpdateByCond(updated, condition *T, updateColumns ...Column[T]) (int64, error)
 
 	// UpdateByCondWithZeroField 通过条件更新，并指定可以更新的零值字段
-	UpdateByCondWithZeroField(updated, condition *T, allowZeroFiledColumns []string) (int64, error)
+	UpdateByCondWithZeroField(updated, condition *T, allowZeroFiledColumns []Column[T]) (int64, error)
 
 	// UpdateByMap 通过Map类型条件更新
 	UpdateByMap(updated, condition map[string]any) (int64, error)
@@ -187,7 +248,8 @@ type IBaseMapper[M BaseMapper[T], T IBaseModel] interface {
 	// UpdateByWhere 通过原始SQL查询条件，更新非零实体字段 Where SQL查询 只需要输入SQL语句和参数 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
 	UpdateByWhere(updated *T, rawWhereSql string, args ...any) (int64, error)
 
-	// DeleteById 通过ID删除相关数据
+	// DeleteById 通过ID删除相关数据 联合主键时id元素为 CompositeKey 或携带主键值的模型结构体
+	// 模型声明软删除字段时 Delete* 仅标记删除 物理删除使用 HardDelete*
 	DeleteById(id ...any) (int64, error)
 
 	// DeleteByCond 通过条件删除 零值字段将被自动忽略
@@ -198,4 +260,120 @@ type IBaseMapper[M BaseMapper[T], T IBaseModel] interface {
 
 	// DeleteByWhere 通过原始SQL删除相关数据 Where SQL查询 只需要输入SQL语句和参数 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
 	DeleteByWhere(rawWhereSql string, args ...any) (int64, error)
+
+	// SelectOneByQuery 通过查询条件构造器查询单条数据
+	SelectOneByQuery(query *Query[T], result *T) (int64, error)
+
+	// SelectByQuery 通过查询条件构造器查询数据
+	SelectByQuery(query *Query[T], result *[]*T) (int64, error)
+
+	// CountByQuery 通过查询条件构造器查询数据总数
+	CountByQuery(query *Query[T]) (int64, error)
+
+	// SelectPageByQuery 通过查询条件构造器分页查询 pageNumber 页码 1开始
+	SelectPageByQuery(query *Query[T], pageNumber, pageSize int, result *[]*T) (total int64, err error)
+
+	// UpdateByQuery 通过查询条件构造器更新所有map中指定的列和值 map的key支持数据库字段名或结构体字段名
+	UpdateByQuery(updated map[string]any, query *Query[T]) (int64, error)
+
+	// DeleteByQuery 通过查询条件构造器删除数据 无查询条件时将拒绝执行
+	DeleteByQuery(query *Query[T]) (int64, error)
+
+	// FindById 通过主键查询数据 未命中时返回 ErrNotFound 联合主键时id为 CompositeKey 或携带主键值的模型结构体
+	FindById(id any) (*T, error)
+
+	// FindByIds 通过主键查询数据 联合主键时id元素为 CompositeKey 或携带主键值的模型结构体
+	FindByIds(ids []any) ([]*T, error)
+
+	// FindOneByCond 通过条件查询单条数据 未命中时返回 ErrNotFound 查询条件零值字段将被自动忽略
+	// specifyColumns 指定只需要查询的数据库字段
+	FindOneByCond(condition *T, specifyColumns ...Column[T]) (*T, error)
+
+	// FindOneByMap 通过指定字段与值查询单条数据 未命中时返回 ErrNotFound
+	// specifyColumns 指定只需要查询的数据库字段
+	FindOneByMap(condition map[string]any, specifyColumns ...Column[T]) (*T, error)
+
+	// FindOneByWhere 通过原始Where SQL查询单条数据 未命中时返回 ErrNotFound
+	FindOneByWhere(rawWhereSql string, args ...any) (*T, error)
+
+	// FindOne 通过查询条件构造器查询单条数据 未命中时返回 ErrNotFound
+	FindOne(query *Query[T]) (*T, error)
+
+	// FindListByCond 通过条件查询数据 未命中时返回空切片 查询条件零值字段将被自动忽略
+	// specifyColumns 指定只需要查询的数据库字段
+	FindListByCond(condition *T, orderBy string, specifyColumns ...Column[T]) ([]*T, error)
+
+	// FindListByMap 通过指定字段与值查询数据 未命中时返回空切片
+	// specifyColumns 指定只需要查询的数据库字段
+	FindListByMap(condition map[string]any, orderBy string, specifyColumns ...Column[T]) ([]*T, error)
+
+	// FindListByWhere 通过原始Where SQL查询数据 未命中时返回空切片
+	FindListByWhere(rawWhereSql, orderBy string, args ...any) ([]*T, error)
+
+	// FindList 通过查询条件构造器查询数据 未命中时返回空切片
+	FindList(query *Query[T]) ([]*T, error)
+
+	// FindPageByCursor 通过查询条件构造器进行游标分页 (keyset pagination) 避免深分页的OFFSET扫描
+	// 排序字段由 query 的 OrderByAsc/OrderByDesc 指定 并自动追加主键作为唯一排序依据 排序字段不应包含NULL值
+	// cursor 为上一次返回的 NextCursor 或 PrevCursor 首页传入空字符串 withTotal 为true时额外查询数据总数
+	FindPageByCursor(query *Query[T], cursor string, size int, withTotal ...bool) (*CursorPage[T], error)
+
+	// FindPage 通过查询条件构造器分页查询 pageNumber 页码 1开始
+	FindPage(query *Query[T], pageNumber, pageSize int, opts ...PageOptions) (*Page[T], error)
+
+	// FindPageByCond 通过条件分页查询 零值字段将被自动忽略
+	// specifyColumns 指定只需要查询的数据库字段 pageNumber 页码 1开始
+	FindPageByCond(condition *T, orderBy string, pageNumber, pageSize int, opts []PageOptions, specifyColumns ...Column[T]) (*Page[T], error)
+
+	// FindPageByMap 通过指定字段与值分页查询 解决零值条件问题
+	// specifyColumns 指定只需要查询的数据库字段 pageNumber 页码 1开始
+	FindPageByMap(condition map[string]any, orderBy string, pageNumber, pageSize int, opts []PageOptions, specifyColumns ...Column[T]) (*Page[T], error)
+
+	// FindPageByWhere 通过原始SQL分页查询 rawWhereSql 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
+	// specifyColumns 指定只需要查询的数据库字段 pageNumber 页码 1开始
+	FindPageByWhere(rawWhereSql, orderBy string, pageNumber, pageSize int, args []any, opts []PageOptions, specifyColumns ...Column[T]) (*Page[T], error)
+
+	// Iterate 通过查询条件构造器流式遍历数据 不会一次性加载全部结果 遍历期间将持有一个数据库连接
+	Iterate(query *Query[T]) iter.Seq2[*T, error]
+
+	// IterateByCond 通过条件流式遍历数据 查询条件零值字段将被自动忽略
+	// specifyColumns 指定只需要查询的数据库字段
+	IterateByCond(condition *T, orderBy string, specifyColumns ...Column[T]) iter.Seq2[*T, error]
+
+	// IterateByWhere 通过原始Where SQL流式遍历数据 只需要输入SQL语句和参数 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
+	IterateByWhere(rawWhereSql, orderBy string, args ...any) iter.Seq2[*T, error]
+
+	// Each 通过查询条件构造器流式遍历数据并逐条回调 回调返回 ErrStopIteration 时提前结束遍历
+	Each(query *Query[T], fn func(record *T) error) error
+
+	// FindInBatches 通过查询条件构造器按主键顺序分批查询数据并回调 每批最多 batchSize 条 回调返回 ErrStopIteration 时提前结束
+	// 分批基于主键条件 (keyset) 而非OFFSET 查询条件中的排序将被忽略 返回已处理的数据条数
+	FindInBatches(query *Query[T], batchSize int, fn func(batch []*T) error) (int64, error)
+
+	// RestoreById 通过ID恢复已软删除的数据 联合主键时id元素为 CompositeKey 或携带主键值的模型结构体
+	RestoreById(id ...any) (int64, error)
+
+	// RestoreByCond 通过条件恢复已软删除的数据 零值字段将被自动忽略
+	RestoreByCond(condition *T) (int64, error)
+
+	// RestoreByWhere 通过原始SQL恢复已软删除的数据 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
+	RestoreByWhere(rawWhereSql string, args ...any) (int64, error)
+
+	// RestoreByMap 通过Map类型条件恢复已软删除的数据
+	RestoreByMap(condition map[string]any) (int64, error)
+
+	// HardDeleteById 通过ID物理删除数据 包含已软删除的数据 联合主键时id元素为 CompositeKey 或携带主键值的模型结构体
+	HardDeleteById(id ...any) (int64, error)
+
+	// HardDeleteByCond 通过条件物理删除数据 零值字段将被自动忽略
+	HardDeleteByCond(condition *T) (int64, error)
+
+	// HardDeleteByWhere 通过原始SQL物理删除数据 例如 where a = 1 则只需要rawWhereSql = "a = ?" args = 1
+	HardDeleteByWhere(rawWhereSql string, args ...any) (int64, error)
+
+	// HardDeleteByMap 通过Map类型条件物理删除数据
+	HardDeleteByMap(condition map[string]any) (int64, error)
+
+	// PurgeDeleted 物理删除所有已软删除的数据 记录删除时间的软删除字段可通过 before 仅清理该时间之前删除的数据
+	PurgeDeleted(before ...time.Time) (int64, error)
 }
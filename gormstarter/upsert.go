@@ -0,0 +1,155 @@
+package gormstarter
+
+import (
+	"slices"
+	"strings"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// OnConflict 新增数据冲突时的处理方式 mysql 生成 ON DUPLICATE KEY UPDATE postgres/sqlite 生成 ON CONFLICT (...) DO UPDATE / DO NOTHING
+type OnConflict[T IBaseModel] struct {
+	// 冲突判断字段 需要为主键或唯一索引 不指定时使用主键 mysql 由表的主键及唯一索引决定 将忽略该配置
+	ConflictColumns []Column[T]
+	// 冲突时需要更新的字段 不指定时更新除主键、自动创建时间及创建人字段外所有参与新增的字段 自动更新时间及更新人字段总是被刷新
+	UpdateColumns []Column[T]
+	// 冲突时不做任何处理 优先级高于 UpdateColumns
+	DoNothing bool
+}
+
+// clause 构建 gorm 冲突处理子句
+func (o OnConflict[T]) clause(b BaseMapper[T]) (clause.OnConflict, error) {
+	var onConflict clause.OnConflict
+	sch, err := b.schema()
+	if err != nil {
+		return onConflict, err
+	}
+	for _, column := range o.ConflictColumns {
+		name, err := lookupColumn(sch, string(column))
+		if err != nil {
+			return onConflict, err
+		}
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: name})
+	}
+	if len(onConflict.Columns) == 0 {
+		for _, field := range sch.PrimaryFields {
+			onConflict.Columns = append(onConflict.Columns, clause.Column{Name: field.DBName})
+		}
+	}
+	operator, err := b.operatorFields()
+	if err != nil {
+		return onConflict, err
+	}
+	t, err := b.tenant()
+	if err != nil {
+		return onConflict, err
+	}
+	switch {
+	case o.DoNothing:
+		onConflict.DoNothing = true
+	case len(o.UpdateColumns) > 0:
+		names := make([]string, 0, len(o.UpdateColumns))
+		for _, column := range o.UpdateColumns {
+			name, err := lookupColumn(sch, string(column))
+			if err != nil {
+				return onConflict, err
+			}
+			// 租户字段不允许通过冲突更新修改
+			if t != nil && name == t.field.DBName {
+				continue
+			}
+			names = append(names, name)
+		}
+		if operator != nil && operator.updatedBy != nil && !slices.Contains(names, operator.updatedBy.DBName) {
+			names = append(names, operator.updatedBy.DBName)
+		}
+		onConflict.DoUpdates = clause.AssignmentColumns(names)
+		// 未指定的自动更新时间字段同样刷新
+		now := b.rawDB().NowFunc()
+		for _, field := range sch.Fields {
+			if field.AutoUpdateTime == 0 || !field.Updatable || slices.Contains(names, field.DBName) {
+				continue
+			}
+			assignment := clause.Assignment{Column: clause.Column{Name: field.DBName}, Value: now}
+			switch field.AutoUpdateTime {
+			case schema.UnixNanosecond:
+				assignment.Value = now.UnixNano()
+			case schema.UnixMillisecond:
+				assignment.Value = now.UnixMilli()
+			case schema.UnixSecond:
+				assignment.Value = now.Unix()
+			}
+			onConflict.DoUpdates = append(onConflict.DoUpdates, assignment)
+		}
+	case operator != nil && operator.createdBy != nil, t != nil:
+		// 与 UpdateAll 规则一致 额外排除创建人及租户字段
+		var names []string
+		for _, field := range sch.Fields {
+			if field.DBName == "" || field.PrimaryKey || field.AutoCreateTime > 0 || !field.Creatable || !field.Updatable {
+				continue
+			}
+			if (operator != nil && field == operator.createdBy) || (t != nil && field == t.field) {
+				continue
+			}
+			if field.HasDefaultValue && field.DefaultValueInterface == nil && !strings.EqualFold(field.DefaultValue, "NULL") {
+				continue
+			}
+			names = append(names, field.DBName)
+		}
+		onConflict.DoUpdates = clause.AssignmentColumns(names)
+	default:
+		// gorm将排除主键及自动创建时间字段 并刷新自动更新时间字段
+		onConflict.UpdateAll = true
+	}
+	if t != nil && !onConflict.DoNothing {
+		if len(onConflict.DoUpdates) == 0 {
+			onConflict.DoNothing = true
+		} else {
+			b.scopeConflictTenant(&onConflict, t)
+		}
+	}
+	return onConflict, nil
+}
+
+// scopeConflictTenant 冲突时仅更新当前租户的数据 避免覆盖其他租户的数据
+// mysql 的 ON DUPLICATE KEY UPDATE 不支持条件 通过条件赋值在冲突数据不属于当前租户时保持原值
+func (b BaseMapper[T]) scopeConflictTenant(onConflict *clause.OnConflict, t *tenant) {
+	if b.rawDB().Dialector.Name() != string(DBTypeMySQL) {
+		onConflict.Where = clause.Where{Exprs: []clause.Expression{t.cond()}}
+		return
+	}
+	column := clause.Column{Name: t.field.DBName}
+	for i, assignment := range onConflict.DoUpdates {
+		value := assignment.Value
+		if excluded, ok := value.(clause.Column); ok && excluded.Table == "excluded" {
+			value = clause.Expr{SQL: "VALUES(?)", Vars: []any{clause.Column{Name: excluded.Name}}}
+		}
+		onConflict.DoUpdates[i].Value = clause.Expr{SQL: "IF(? = ?, ?, ?)", Vars: []any{column, t.value, value, assignment.Column}}
+	}
+}
+
+func (b BaseMapper[T]) insertOnConflict(value any, onConflict OnConflict[T]) (int64, error) {
+	c, err := onConflict.clause(b)
+	if err != nil {
+		return 0, err
+	}
+	return checkResult(b.audit(b.rawDB()).Clauses(c).Create(value))
+}
+
+// InsertOnConflict 新增数据 冲突时按 onConflict 更新指定字段或不做处理 零值也将参与保存
+// 与 InsertOrUpdateByPrimaryKey 不同 默认不会覆盖创建时间 且支持唯一索引冲突
+func (b BaseMapper[T]) InsertOnConflict(entity *T, onConflict OnConflict[T]) (int64, error) {
+	if err := b.stampCreate(entity); err != nil {
+		return 0, err
+	}
+	return b.insertOnConflict(entity, onConflict)
+}
+
+// InsertBatchOnConflict 批量新增数据 冲突时按 onConflict 更新指定字段或不做处理 零值也将参与保存
+func (b BaseMapper[T]) InsertBatchOnConflict(entities *[]*T, onConflict OnConflict[T]) (int64, error) {
+	if err := b.stampCreate(*entities...); err != nil {
+		return 0, err
+	}
+	return b.insertOnConflict(entities, onConflict)
+}
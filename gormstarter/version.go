@@ -0,0 +1,128 @@
+package gormstarter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrOptimisticLock 乐观锁更新失败 数据已被其他操作修改或不存在
+var ErrOptimisticLock = errors.New("optimistic lock failed: record has been modified or does not exist")
+
+// optimisticLock 乐观锁更新上下文
+type optimisticLock struct {
+	field   *schema.Field
+	cond    clause.Expression
+	restore func()
+}
+
+// versionField 获取模型中通过 gorm:"version" 标签声明的版本号字段 未声明时返回nil
+func (b BaseMapper[T]) versionField() (*schema.Field, error) {
+	sch, err := b.schema()
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range sch.Fields {
+		if _, ok := field.TagSettings["VERSION"]; ok {
+			return field, nil
+		}
+	}
+	return nil, nil
+}
+
+// lockEntity 读取实体当前版本号作为更新条件 并将实体版本号加1 模型未声明版本号字段时返回nil
+func (b BaseMapper[T]) lockEntity(entity *T) (*optimisticLock, error) {
+	field, err := b.versionField()
+	if err != nil || field == nil {
+		return nil, err
+	}
+	rv := reflect.ValueOf(entity).Elem()
+	value := reflect.Indirect(field.ReflectValueOf(b.context(), rv))
+	current, next, err := nextVersion(value)
+	if err != nil {
+		return nil, fmt.Errorf("version field %s: %w", field.Name, err)
+	}
+	if err = field.Set(b.context(), rv, next); err != nil {
+		return nil, err
+	}
+	return &optimisticLock{
+		field: field,
+		cond:  clause.Eq{Column: clause.Column{Name: field.DBName}, Value: current},
+		restore: func() {
+			_ = field.Set(b.context(), rv, current)
+		},
+	}, nil
+}
+
+// lockMap 读取map中的版本号作为更新条件 并将map中的版本号加1 map中未指定版本号时仅将版本号加1 模型未声明版本号字段时返回nil
+func (b BaseMapper[T]) lockMap(updated map[string]any) (*optimisticLock, error) {
+	field, err := b.versionField()
+	if err != nil || field == nil {
+		return nil, err
+	}
+	for key, value := range updated {
+		if key != field.DBName && key != field.Name {
+			continue
+		}
+		current, next, err := nextVersion(reflect.Indirect(reflect.ValueOf(value)))
+		if err != nil {
+			return nil, fmt.Errorf("version field %s: %w", field.Name, err)
+		}
+		updated[key] = next
+		return &optimisticLock{
+			field: field,
+			cond:  clause.Eq{Column: clause.Column{Name: field.DBName}, Value: current},
+			restore: func() {
+				updated[key] = current
+			},
+		}, nil
+	}
+	updated[field.DBName] = gorm.Expr("? + 1", clause.Column{Name: field.DBName})
+	return &optimisticLock{
+		field: field,
+		restore: func() {
+			delete(updated, field.DBName)
+		},
+	}, nil
+}
+
+// nextVersion 获取当前版本号及加1后的版本号 仅支持整数类型
+func nextVersion(value reflect.Value) (current, next any, err error) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v := value.Int()
+		return v, v + 1, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v := value.Uint()
+		return v, v + 1, nil
+	}
+	return nil, nil, errors.New("version must be an integer")
+}
+
+// apply 添加版本号条件
+func (l *optimisticLock) apply(db *gorm.DB) *gorm.DB {
+	if l == nil || l.cond == nil {
+		return db
+	}
+	return db.Where(l.cond)
+}
+
+// check 校验更新结果 未更新任何数据时返回 ErrOptimisticLock 失败时还原版本号
+func (l *optimisticLock) check(rows int64, err error) (int64, error) {
+	if l == nil {
+		return rows, err
+	}
+	if err != nil {
+		l.restore()
+		return rows, err
+	}
+	if rows == 0 && l.cond != nil {
+		l.restore()
+		return 0, ErrOptimisticLock
+	}
+	return rows, nil
+}
@@ -0,0 +1,153 @@
+// Code generated by gormstarter-gen. DO NOT EDIT.
+
+package model
+
+import "github.com/golang-acexy/starter-gorm/gormstarter"
+
+// StudentColumns Student 的数据库字段
+var StudentColumns = struct {
+	ID        gormstarter.Column[Student]
+	CreatedAt gormstarter.Column[Student]
+	UpdatedAt gormstarter.Column[Student]
+	Name      gormstarter.Column[Student]
+	Sex       gormstarter.Column[Student]
+	TeacherId gormstarter.Column[Student]
+}{
+	ID:        "id",
+	CreatedAt: "create_time",
+	UpdatedAt: "update_time",
+	Name:      "name",
+	Sex:       "sex",
+	TeacherId: "teacher_id",
+}
+
+// TeacherColumns Teacher 的数据库字段
+var TeacherColumns = struct {
+	ID        gormstarter.Column[Teacher]
+	CreatedAt gormstarter.Column[Teacher]
+	UpdatedAt gormstarter.Column[Teacher]
+	Name      gormstarter.Column[Teacher]
+	Sex       gormstarter.Column[Teacher]
+	Age       gormstarter.Column[Teacher]
+	ClassNo   gormstarter.Column[Teacher]
+	Version   gormstarter.Column[Teacher]
+}{
+	ID:        "id",
+	CreatedAt: "create_time",
+	UpdatedAt: "update_time",
+	Name:      "name",
+	Sex:       "sex",
+	Age:       "age",
+	ClassNo:   "class_no",
+	Version:   "version",
+}
+
+// AuditTeacherColumns AuditTeacher 的数据库字段
+var AuditTeacherColumns = struct {
+	ID        gormstarter.Column[AuditTeacher]
+	CreatedAt gormstarter.Column[AuditTeacher]
+	UpdatedAt gormstarter.Column[AuditTeacher]
+	Name      gormstarter.Column[AuditTeacher]
+	Sex       gormstarter.Column[AuditTeacher]
+	Age       gormstarter.Column[AuditTeacher]
+	ClassNo   gormstarter.Column[AuditTeacher]
+	Version   gormstarter.Column[AuditTeacher]
+}{
+	ID:        "id",
+	CreatedAt: "create_time",
+	UpdatedAt: "update_time",
+	Name:      "name",
+	Sex:       "sex",
+	Age:       "age",
+	ClassNo:   "class_no",
+	Version:   "version",
+}
+
+// TeacherClassColumns TeacherClass 的数据库字段
+var TeacherClassColumns = struct {
+	TeacherId gormstarter.Column[TeacherClass]
+	ClassNo   gormstarter.Column[TeacherClass]
+	Remark    gormstarter.Column[TeacherClass]
+	Deleted   gormstarter.Column[TeacherClass]
+}{
+	TeacherId: "teacher_id",
+	ClassNo:   "class_no",
+	Remark:    "remark",
+	Deleted:   "deleted",
+}
+
+// GradeColumns Grade 的数据库字段
+var GradeColumns = struct {
+	StudentNo gormstarter.Column[Grade]
+	Score     gormstarter.Column[Grade]
+}{
+	StudentNo: "student_no",
+	Score:     "score",
+}
+
+// VisitColumns Visit 的数据库字段
+var VisitColumns = struct {
+	Page gormstarter.Column[Visit]
+}{
+	Page: "page",
+}
+
+// MemberColumns Member 的数据库字段
+var MemberColumns = struct {
+	ID        gormstarter.Column[Member]
+	Name      gormstarter.Column[Member]
+	Sex       gormstarter.Column[Member]
+	DeletedAt gormstarter.Column[Member]
+}{
+	ID:        "id",
+	Name:      "name",
+	Sex:       "sex",
+	DeletedAt: "deleted_at",
+}
+
+// StaffColumns Staff 的数据库字段
+var StaffColumns = struct {
+	ID        gormstarter.Column[Staff]
+	Name      gormstarter.Column[Staff]
+	Sex       gormstarter.Column[Staff]
+	DeletedAt gormstarter.Column[Staff]
+	CreatedBy gormstarter.Column[Staff]
+	UpdatedBy gormstarter.Column[Staff]
+	DeletedBy gormstarter.Column[Staff]
+}{
+	ID:        "id",
+	Name:      "name",
+	Sex:       "sex",
+	DeletedAt: "deleted_at",
+	CreatedBy: "created_by",
+	UpdatedBy: "updated_by",
+	DeletedBy: "deleted_by",
+}
+
+// CourseColumns Course 的数据库字段
+var CourseColumns = struct {
+	ID       gormstarter.Column[Course]
+	TenantId gormstarter.Column[Course]
+	Name     gormstarter.Column[Course]
+}{
+	ID:       "id",
+	TenantId: "tenant_id",
+	Name:     "name",
+}
+
+// EmployeeColumns Employee 的数据库字段
+var EmployeeColumns = struct {
+	ID        gormstarter.Column[Employee]
+	CreatedAt gormstarter.Column[Employee]
+	UpdatedAt gormstarter.Column[Employee]
+	Name      gormstarter.Column[Employee]
+	Sex       gormstarter.Column[Employee]
+	LeaderId  gormstarter.Column[Employee]
+}{
+	ID:        "id",
+	CreatedAt: "created_at",
+	UpdatedAt: "updated_at",
+	Name:      "name",
+	Sex:       "sex",
+	LeaderId:  "leader_id",
+}
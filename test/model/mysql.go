@@ -1,5 +1,7 @@
 package model
 
+//go:generate go run github.com/golang-acexy/starter-gorm/cmd/gormstarter-gen
+
 import (
 	"time"
 
@@ -31,6 +33,7 @@ type Teacher struct {
 	Sex       uint
 	Age       uint
 	ClassNo   uint
+	Version   uint `gorm:"version"` // 乐观锁版本号
 }
 
 func (Teacher) TableName() string {
@@ -57,3 +60,124 @@ func (t TeacherMapper) WithTxMapper(tx *gorm.DB) TeacherMapper {
 		t.BaseMapper.GetBaseMapperWithTx(tx),
 	}
 }
+
+// AuditTeacher 记录变更历史 与Teacher使用同一张表
+type AuditTeacher struct {
+	ID        uint64                `gorm:"primaryKey"`
+	CreatedAt gormstarter.Timestamp `gorm:"column:create_time;<-:false"`
+	UpdatedAt gormstarter.Timestamp `gorm:"column:update_time;<-:update"`
+	Name      string
+	Sex       uint
+	Age       uint
+	ClassNo   uint
+	Version   uint `gorm:"version"`
+}
+
+func (AuditTeacher) TableName() string {
+	return "demo_teacher"
+}
+
+func (AuditTeacher) AuditEnabled() bool {
+	return true
+}
+
+type AuditTeacherMapper struct {
+	gormstarter.BaseMapper[AuditTeacher]
+}
+
+// TeacherClass 联合主键
+type TeacherClass struct {
+	TeacherId uint64 `gorm:"primaryKey"`
+	ClassNo   uint   `gorm:"primaryKey"`
+	Remark    string
+	Deleted   bool `gorm:"softDelete"` // 软删除 标记删除
+}
+
+func (TeacherClass) TableName() string {
+	return "demo_teacher_class"
+}
+
+type TeacherClassMapper struct {
+	gormstarter.BaseMapper[TeacherClass]
+}
+
+// Grade 自定义主键字段名
+type Grade struct {
+	StudentNo uint64 `gorm:"primaryKey;autoIncrement:false"`
+	Score     uint
+}
+
+func (Grade) TableName() string {
+	return "demo_grade"
+}
+
+type GradeMapper struct {
+	gormstarter.BaseMapper[Grade]
+}
+
+// Visit 未声明主键
+type Visit struct {
+	Page string
+}
+
+func (Visit) TableName() string {
+	return "demo_visit"
+}
+
+type VisitMapper struct {
+	gormstarter.BaseMapper[Visit]
+}
+
+// Member 软删除 记录删除时间
+type Member struct {
+	ID        uint `gorm:"primaryKey"`
+	Name      string
+	Sex       uint
+	DeletedAt gorm.DeletedAt
+}
+
+func (Member) TableName() string {
+	return "demo_member"
+}
+
+type MemberMapper struct {
+	gormstarter.BaseMapper[Member]
+}
+
+// Staff 自动填充操作人
+type Staff struct {
+	ID        uint `gorm:"primaryKey"`
+	Name      string
+	Sex       uint
+	DeletedAt gorm.DeletedAt // 软删除时填充删除人
+	CreatedBy string         // 操作人 由 GormConfig.OperatorExtractor 自动填充
+	UpdatedBy string
+	DeletedBy string
+}
+
+func (Staff) TableName() string {
+	return "demo_staff"
+}
+
+type StaffMapper struct {
+	gormstarter.BaseMapper[Staff]
+}
+
+// Course 多租户 按 tenant_id 隔离数据
+type Course struct {
+	ID       uint64 `gorm:"primaryKey"`
+	TenantId string
+	Name     string
+}
+
+func (Course) TableName() string {
+	return "demo_course"
+}
+
+func (Course) TenantColumn() string {
+	return "tenant_id"
+}
+
+type CourseMapper struct {
+	gormstarter.BaseMapper[Course]
+}
@@ -28,3 +28,15 @@ func TestPostgresSelect(t *testing.T) {
 	}
 	fmt.Println(employeeMapper.SelectOneByCond(&employee, &employee))
 }
+
+func TestNamedDataSource(t *testing.T) {
+	var teachers []*model.Teacher
+	db := gormstarter.RawGormDBByName("billing")
+	db.Table(model.Teacher{}.TableName()).Scan(&teachers)
+	fmt.Println(json.ToString(teachers))
+
+	var teacherMapper model.TeacherMapper
+	billingMapper := teacherMapper.GetBaseMapperWithDataSource("billing")
+	fmt.Println(teacherMapper.DataSource(), billingMapper.DataSource())
+	fmt.Println(billingMapper.SelectByCond(&model.Teacher{Sex: 1}, "id desc", &teachers))
+}
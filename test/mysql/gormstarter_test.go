@@ -20,12 +20,15 @@ func init() {
 		&gormstarter.GormStarter{
 			LazyConfig: func() gormstarter.GormConfig {
 				return gormstarter.GormConfig{
-					Username:      "root",
-					Password:      "root",
-					Database:      "test",
-					Host:          "127.0.0.1",
-					Port:          13306,
-					SQLoggerLevel: logger.ErrorLevel,
+					Username:        "root",
+					Password:        "root",
+					Database:        "test",
+					Host:            "127.0.0.1",
+					Port:            13306,
+					SQLoggerLevel:   logger.ErrorLevel,
+					MaxOpenConns:    20,
+					MaxIdleConns:    5,
+					CreateBatchSize: 500,
 					InitFunc: func(instance *gorm.DB) {
 						fmt.Println(logger.IsLevelEnabled(logger.TraceLevel))
 						//fmt.Println(instance.Config)
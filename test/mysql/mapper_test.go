@@ -1,6 +1,7 @@
 package mysql
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -57,7 +58,7 @@ func TestBaseSave(t *testing.T) {
 		Sex:  1,
 		Name: "name",
 	}
-	fmt.Println(bm.InsertOrUpdateByPrimaryKey(&teacher5, "create_time"))
+	fmt.Println(bm.InsertOrUpdateByPrimaryKey(&teacher5, model.TeacherColumns.CreatedAt))
 	fmt.Println("saved id", teacher5.ID)
 }
 
@@ -66,7 +67,7 @@ func TestBatch(t *testing.T) {
 	teacher1 := model.Teacher{Sex: 1}
 	v := []*model.Teacher{&teacher, &teacher1}
 	bm := model.TeacherMapper{}
-	bm.InsertBatch(&v, "create_time")
+	bm.InsertBatch(&v, model.TeacherColumns.CreatedAt)
 
 }
 func TestModifyById(t *testing.T) {
@@ -218,7 +219,7 @@ func TestUpdateByCondWithZeroField(t *testing.T) {
 	bm := model.TeacherMapper{}
 	updated := model.Teacher{Name: "1", Age: 0}
 	condition := model.Teacher{Name: "2", Age: 0}
-	fmt.Println(bm.UpdateByCondWithZeroField(&updated, &condition, []string{"ClassNo"}))
+	fmt.Println(bm.UpdateByCondWithZeroField(&updated, &condition, []gormstarter.Column[model.Teacher]{"ClassNo"}))
 }
 
 func TestUpdateByCondMap(t *testing.T) {
@@ -247,3 +248,32 @@ func TestTransaction(t *testing.T) {
 	fmt.Println(mpTx.ById(teacher.ID))
 	tx.Commit()
 }
+
+func TestWithContext(t *testing.T) {
+	var bm model.TeacherMapper
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var teachers []*model.Teacher
+	// 超时后查询将被取消
+	fmt.Println(bm.WithContext(ctx).SelectByGorm(&teachers, func(db *gorm.DB) {
+		db.Where("sleep(2) = 0")
+	}))
+	fmt.Println(bm.WithContext(context.Background()).CountByCond(&model.Teacher{Sex: 1}))
+}
+
+func TestCompositeKey(t *testing.T) {
+	var bm model.TeacherClassMapper
+	fmt.Println(bm.Insert(&model.TeacherClass{TeacherId: 1, ClassNo: 1, Remark: "a"}))
+	fmt.Println(bm.Insert(&model.TeacherClass{TeacherId: 1, ClassNo: 2, Remark: "b"}))
+
+	var teacherClass model.TeacherClass
+	fmt.Println(bm.SelectById(gormstarter.CompositeKey{1, 1}, &teacherClass))
+	fmt.Println(json.ToString(teacherClass))
+
+	var teacherClasses []*model.TeacherClass
+	fmt.Println(bm.SelectByIds([]any{gormstarter.CompositeKey{1, 1}, &model.TeacherClass{TeacherId: 1, ClassNo: 2}}, &teacherClasses))
+	fmt.Println(json.ToString(teacherClasses))
+
+	fmt.Println(bm.UpdateByIdUseMap(map[string]any{"remark": "c"}, gormstarter.CompositeKey{1, 2}))
+	fmt.Println(bm.DeleteById(gormstarter.CompositeKey{1, 1}, gormstarter.CompositeKey{1, 2}))
+}
@@ -20,7 +20,80 @@ create table test.demo_teacher
     name        varchar(10) default ''  not null,
     sex         char        default '1' not null,
     age         int         default 0   not null,
-    class_no  varchar(10)
+    class_no  varchar(10),
+    version     int unsigned default 0  not null
 ) engine = InnoDB
     charset = utf8mb4;
 
+
+create table test.demo_teacher_class
+(
+    teacher_id bigint unsigned not null,
+    class_no   int unsigned    not null,
+    remark     varchar(64) default '' not null,
+    deleted    tinyint(1)  default 0  not null,
+    primary key (teacher_id, class_no)
+) engine = InnoDB
+    charset = utf8mb4;
+
+create table test.demo_grade
+(
+    student_no bigint unsigned not null
+        primary key,
+    score      int unsigned default 0 not null
+) engine = InnoDB
+    charset = utf8mb4;
+
+create table test.demo_visit
+(
+    page varchar(64) default '' not null
+) engine = InnoDB
+    charset = utf8mb4;
+
+create table test.demo_member
+(
+    id         bigint unsigned auto_increment
+        primary key,
+    name       varchar(10) default '' not null,
+    sex        int unsigned default 0 not null,
+    deleted_at datetime    null
+) engine = InnoDB
+    charset = utf8mb4;
+
+create table test.demo_staff
+(
+    id         bigint unsigned auto_increment
+        primary key,
+    name       varchar(10) default '' not null,
+    sex        int unsigned default 0 not null,
+    deleted_at datetime    null,
+    created_by varchar(32) default '' not null,
+    updated_by varchar(32) default '' not null,
+    deleted_by varchar(32) default '' not null
+) engine = InnoDB
+    charset = utf8mb4;
+
+create table test.demo_course
+(
+    id        bigint unsigned auto_increment
+        primary key,
+    tenant_id varchar(32) not null,
+    name      varchar(64) default '' not null,
+    key idx_tenant_id (tenant_id)
+) engine = InnoDB
+    charset = utf8mb4;
+
+create table test.audit_log
+(
+    id          bigint unsigned auto_increment
+        primary key,
+    table_name  varchar(64)  not null,
+    primary_key varchar(255) not null,
+    operation   varchar(16)  not null,
+    before_data text         null,
+    after_data  text         null,
+    operator    varchar(64)  default '' not null,
+    created_at  datetime     null,
+    index idx_audit_log_table (table_name, primary_key)
+) engine = InnoDB
+    charset = utf8mb4;
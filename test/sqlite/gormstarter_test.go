@@ -0,0 +1,181 @@
+package sqlite
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/acexy/golang-toolkit/logger"
+	"github.com/golang-acexy/starter-gorm/gormstarter"
+	"github.com/golang-acexy/starter-gorm/test/model"
+	"github.com/golang-acexy/starter-parent/parent"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+//go:embed sqlite.sql
+var ddl string
+
+var starterLoader *parent.StarterLoader
+
+type operatorKey struct{}
+
+type tenantKey struct{}
+
+func TestMain(m *testing.M) {
+	logger.EnableConsole(logger.DebugLevel)
+	starterLoader = parent.NewStarterLoader([]parent.Starter{
+		&gormstarter.GormStarter{
+			Config: gormstarter.GormConfig{
+				DBType: gormstarter.DBTypeSQLite,
+				InitFunc: func(instance *gorm.DB) {
+					if err := instance.Exec(ddl).Error; err != nil {
+						panic(err)
+					}
+				},
+				OperatorExtractor: func(ctx context.Context) any {
+					return ctx.Value(operatorKey{})
+				},
+				TenantResolver: func(ctx context.Context) any {
+					return ctx.Value(tenantKey{})
+				},
+			},
+		},
+	})
+	if err := starterLoader.Start(); err != nil {
+		fmt.Printf("%+v\n", err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	_, _ = starterLoader.Stop(time.Second * 5)
+	os.Exit(code)
+}
+
+func TestFileDatabase(t *testing.T) {
+	starter := &gormstarter.GormStarter{
+		Config: gormstarter.GormConfig{
+			Name:       "sqlite-file",
+			DBType:     gormstarter.DBTypeSQLite,
+			SQLiteFile: t.TempDir() + "/test.db",
+		},
+	}
+	db, err := starter.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v int
+	if err = db.(*gorm.DB).Raw("select 1").Scan(&v).Error; err != nil || v != 1 {
+		t.Fatal(v, err)
+	}
+	if _, stopped, err := starter.Stop(time.Second); !stopped || err != nil {
+		t.Fatal(stopped, err)
+	}
+}
+
+func TestRegisterDialect(t *testing.T) {
+	const dbType gormstarter.DBType = "custom-sqlite"
+	gormstarter.RegisterDialect(dbType, func(config *gormstarter.GormConfig) (gorm.Dialector, error) {
+		return sqlite.Open("file:" + config.Database + "?mode=memory"), nil
+	})
+	starter := &gormstarter.GormStarter{
+		Config: gormstarter.GormConfig{
+			DBType:       dbType,
+			Database:     "custom",
+			MaxOpenConns: 1,
+		},
+	}
+	if _, err := starter.Start(); err != nil {
+		t.Fatal(err)
+	}
+	var v int
+	if err := gormstarter.RawGormDBByName(string(dbType)).Raw("select 2").Scan(&v).Error; err != nil || v != 2 {
+		t.Fatal(v, err)
+	}
+	if _, _, err := starter.Stop(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	starter = &gormstarter.GormStarter{Config: gormstarter.GormConfig{DBType: "unknown"}}
+	if _, err := starter.Start(); err == nil {
+		t.Fatal("expect not supported database type")
+	}
+}
+
+func TestDSN(t *testing.T) {
+	starter := &gormstarter.GormStarter{
+		Config: gormstarter.GormConfig{
+			Name:   "sqlite-dsn",
+			DBType: gormstarter.DBTypeSQLite,
+			DSN:    "file:dsn?mode=memory&cache=shared",
+		},
+	}
+	if _, err := starter.Start(); err != nil {
+		t.Fatal(err)
+	}
+	var v int
+	if err := gormstarter.RawGormDBByName("sqlite-dsn").Raw("select 3").Scan(&v).Error; err != nil || v != 3 {
+		t.Fatal(v, err)
+	}
+	if _, _, err := starter.Stop(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplicas(t *testing.T) {
+	starter := &gormstarter.GormStarter{
+		Config: gormstarter.GormConfig{
+			Name:     "sqlite-primary",
+			DBType:   gormstarter.DBTypeSQLite,
+			DSN:      "file:primary?mode=memory&cache=shared",
+			Replicas: []gormstarter.ReplicaConfig{{DSN: "file:replica?mode=memory&cache=shared"}},
+		},
+	}
+	if _, err := starter.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_, _, _ = starter.Stop(time.Second)
+	}()
+	// 保持从库内存数据库存活 并模拟已同步的数据
+	replica, err := gorm.Open(sqlite.Open("file:replica?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = replica.Exec(ddl).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err = gormstarter.RawGormDBByName("sqlite-primary").Exec(ddl).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	bm := model.TeacherMapper{}.GetBaseMapperWithDataSource("sqlite-primary")
+	teacher := model.Teacher{Name: "primary"}
+	if _, err = bm.Insert(&teacher); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := bm.CountByCond(&model.Teacher{Name: "primary"}); err != nil || count != 0 {
+		t.Fatal("select should route to replica", count, err)
+	}
+	if count, err := bm.WithPrimary().CountByCond(&model.Teacher{Name: "primary"}); err != nil || count != 1 {
+		t.Fatal("select should route to primary", count, err)
+	}
+	tx := bm.NewBaseMapperWithTx()
+	if count, err := tx.CountByCond(&model.Teacher{Name: "primary"}); err != nil || count != 1 {
+		t.Fatal("select in transaction should route to primary", count, err)
+	}
+	tx.CurrentGorm().Rollback()
+	// 内存数据库的从库需要显式指定DSN
+	memory := &gormstarter.GormStarter{
+		Config: gormstarter.GormConfig{
+			Name:     "sqlite-memory",
+			DBType:   gormstarter.DBTypeSQLite,
+			Replicas: []gormstarter.ReplicaConfig{{}},
+		},
+	}
+	if _, err = memory.Start(); err == nil {
+		t.Fatal("expect in-memory sqlite replica error")
+	}
+}
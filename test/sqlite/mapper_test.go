@@ -0,0 +1,1352 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-acexy/starter-gorm/gormstarter"
+	"github.com/golang-acexy/starter-gorm/test/model"
+	"gorm.io/gorm"
+)
+
+func TestInsertAndSelect(t *testing.T) {
+	var bm model.TeacherMapper
+	teacher := model.Teacher{Name: "insert", Age: 12, Sex: 1, ClassNo: 12}
+	if _, err := bm.Insert(&teacher); err != nil {
+		t.Fatal(err)
+	}
+	if teacher.ID == 0 {
+		t.Fatal("primary key not returned")
+	}
+	var selected model.Teacher
+	if rows, err := bm.SelectById(teacher.ID, &selected); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if selected.Name != "insert" || selected.CreatedAt.IsZero() {
+		t.Fatalf("%+v", selected)
+	}
+
+	noZero := model.Teacher{Name: "insert", Age: 13}
+	if _, err := bm.InsertWithoutZeroField(&noZero); err != nil {
+		t.Fatal(err)
+	}
+	var teachers []*model.Teacher
+	if _, err := bm.SelectByIds([]any{teacher.ID, noZero.ID}, &teachers); err != nil || len(teachers) != 2 {
+		t.Fatal(len(teachers), err)
+	}
+	teachers = nil
+	if _, err := bm.SelectByCond(&model.Teacher{Name: "insert"}, "id desc", &teachers, "id", "age"); err != nil || len(teachers) != 2 {
+		t.Fatal(len(teachers), err)
+	}
+	if teachers[0].Age != 13 || teachers[0].Name != "" {
+		t.Fatalf("%+v", teachers[0])
+	}
+	teachers = nil
+	if _, err := bm.SelectByMap(map[string]any{"name": "insert", "age": 13}, "", &teachers); err != nil || len(teachers) != 1 {
+		t.Fatal(len(teachers), err)
+	}
+	teachers = nil
+	if _, err := bm.SelectByWhere("name = ? and age > ?", "", &teachers, "insert", 12); err != nil || len(teachers) != 1 {
+		t.Fatal(len(teachers), err)
+	}
+	teachers = nil
+	if _, err := bm.SelectByGorm(&teachers, func(db *gorm.DB) {
+		db.Where("name = ?", "insert").Order("id")
+	}); err != nil || len(teachers) != 2 {
+		t.Fatal(len(teachers), err)
+	}
+}
+
+func TestInsertBatchAndCount(t *testing.T) {
+	var bm model.TeacherMapper
+	teachers := []*model.Teacher{{Name: "batch", Sex: 1}, {Name: "batch", Sex: 0}, {Name: "batch", Sex: 1}}
+	if rows, err := bm.InsertBatch(&teachers); err != nil || rows != 3 {
+		t.Fatal(rows, err)
+	}
+	if count, err := bm.CountByCond(&model.Teacher{Name: "batch", Sex: 1}); err != nil || count != 2 {
+		t.Fatal(count, err)
+	}
+	if count, err := bm.CountByMap(map[string]any{"name": "batch", "sex": 0}); err != nil || count != 1 {
+		t.Fatal(count, err)
+	}
+	if count, err := bm.CountByWhere("name = ?", "batch"); err != nil || count != 3 {
+		t.Fatal(count, err)
+	}
+}
+
+func TestSelectPage(t *testing.T) {
+	var bm model.TeacherMapper
+	teachers := []*model.Teacher{{Name: "page"}, {Name: "page"}, {Name: "page"}, {Name: "page"}, {Name: "page"}}
+	if _, err := bm.InsertBatch(&teachers); err != nil {
+		t.Fatal(err)
+	}
+	result := new([]*model.Teacher)
+	total, err := bm.SelectPageByCond(&model.Teacher{Name: "page"}, "id", 2, 2, result)
+	if err != nil || total != 5 || len(*result) != 2 || (*result)[0].ID != teachers[2].ID {
+		t.Fatal(total, err)
+	}
+	result = new([]*model.Teacher)
+	total, err = bm.SelectPageByWhere("name = ?", "id", 3, 2, result, []any{"page"})
+	if err != nil || total != 5 || len(*result) != 1 {
+		t.Fatal(total, err)
+	}
+	if _, err = bm.SelectPageByMap(map[string]any{"name": "page"}, "", 0, 2, result); err == nil {
+		t.Fatal("expect page param error")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	var bm model.TeacherMapper
+	teacher := model.Teacher{Name: "update", Age: 20, Sex: 1}
+	if _, err := bm.Insert(&teacher); err != nil {
+		t.Fatal(err)
+	}
+	updated := model.Teacher{ID: teacher.ID, Name: "updated", Sex: 0}
+	if rows, err := bm.UpdateById(&updated, "name", "sex"); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if rows, err := bm.UpdateByIdUseMap(map[string]any{"age": 0}, teacher.ID); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	var selected model.Teacher
+	if _, err := bm.SelectById(teacher.ID, &selected); err != nil {
+		t.Fatal(err)
+	}
+	if selected.Name != "updated" || selected.Sex != 0 || selected.Age != 0 {
+		t.Fatalf("%+v", selected)
+	}
+	if rows, err := bm.UpdateByCond(&model.Teacher{Age: 30}, &model.Teacher{Name: "updated"}); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if rows, err := bm.UpdateByMap(map[string]any{"age": 31}, map[string]any{"name": "updated"}); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if rows, err := bm.UpdateByWhere(&model.Teacher{Age: 32}, "name = ?", "updated"); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	var bm model.TeacherMapper
+	teachers := []*model.Teacher{{Name: "delete", Age: 1}, {Name: "delete", Age: 2}, {Name: "delete", Age: 3}, {Name: "delete", Age: 4}}
+	if _, err := bm.InsertBatch(&teachers); err != nil {
+		t.Fatal(err)
+	}
+	if rows, err := bm.DeleteById(teachers[0].ID); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if rows, err := bm.DeleteByCond(&model.Teacher{Name: "delete", Age: 2}); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if rows, err := bm.DeleteByMap(map[string]any{"name": "delete", "age": 3}); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if rows, err := bm.DeleteByWhere("name = ?", "delete"); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+}
+
+func TestCustomPrimaryKey(t *testing.T) {
+	var bm model.GradeMapper
+	grades := []*model.Grade{{StudentNo: 1001, Score: 60}, {StudentNo: 1002, Score: 70}}
+	if _, err := bm.InsertBatch(&grades); err != nil {
+		t.Fatal(err)
+	}
+	var grade model.Grade
+	if rows, err := bm.SelectById(1002, &grade); err != nil || rows != 1 || grade.Score != 70 {
+		t.Fatal(rows, err, grade)
+	}
+	if rows, err := bm.UpdateById(&model.Grade{StudentNo: 1001, Score: 90}); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if found, err := bm.FindById(1001); err != nil || found.Score != 90 {
+		t.Fatal(found, err)
+	}
+	if rows, err := bm.DeleteById(1001, 1002); err != nil || rows != 2 {
+		t.Fatal(rows, err)
+	}
+
+	var vm model.VisitMapper
+	var visit model.Visit
+	if _, err := vm.SelectById(1, &visit); !errors.Is(err, gormstarter.ErrMissingPrimaryKey) {
+		t.Fatal(err)
+	}
+	if _, err := vm.DeleteById(1); !errors.Is(err, gormstarter.ErrMissingPrimaryKey) {
+		t.Fatal(err)
+	}
+}
+
+func TestCompositeKey(t *testing.T) {
+	var bm model.TeacherClassMapper
+	teacherClasses := []*model.TeacherClass{{TeacherId: 1, ClassNo: 1}, {TeacherId: 1, ClassNo: 2}, {TeacherId: 2, ClassNo: 1}}
+	if _, err := bm.InsertBatch(&teacherClasses); err != nil {
+		t.Fatal(err)
+	}
+	var teacherClass model.TeacherClass
+	if rows, err := bm.SelectById(gormstarter.CompositeKey{1, 2}, &teacherClass); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	var selected []*model.TeacherClass
+	if _, err := bm.SelectByIds([]any{gormstarter.CompositeKey{1, 1}, &model.TeacherClass{TeacherId: 2, ClassNo: 1}}, &selected); err != nil || len(selected) != 2 {
+		t.Fatal(len(selected), err)
+	}
+	if _, err := bm.SelectById(1, &teacherClass); err == nil {
+		t.Fatal("expect composite primary key error")
+	}
+	if rows, err := bm.UpdateByIdUseMap(map[string]any{"remark": "updated"}, gormstarter.CompositeKey{2, 1}); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if rows, err := bm.DeleteById(gormstarter.CompositeKey{1, 1}, gormstarter.CompositeKey{1, 2}); err != nil || rows != 2 {
+		t.Fatal(rows, err)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	var bm model.TeacherMapper
+	txMapper := bm.NewBaseMapperWithTx()
+	teacher := model.Teacher{Name: "tx"}
+	if _, err := txMapper.Insert(&teacher); err != nil {
+		t.Fatal(err)
+	}
+	txMapper.CurrentGorm().Rollback()
+	if count, err := bm.CountByCond(&model.Teacher{Name: "tx"}); err != nil || count != 0 {
+		t.Fatal(count, err)
+	}
+}
+
+func TestDeclarativeTransaction(t *testing.T) {
+	var bm model.TeacherMapper
+	err := gormstarter.Transaction(context.Background(), func(ctx context.Context) error {
+		if _, err := bm.WithContext(ctx).Insert(&model.Teacher{Name: "declarative"}); err != nil {
+			return err
+		}
+		// 嵌套调用加入已有事务
+		return gormstarter.Transaction(ctx, func(ctx context.Context) error {
+			_, err := bm.WithContext(ctx).Insert(&model.Teacher{Name: "declarative"})
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, err := bm.CountByCond(&model.Teacher{Name: "declarative"}); err != nil || count != 2 {
+		t.Fatal(count, err)
+	}
+
+	rollback := errors.New("rollback")
+	err = gormstarter.Transaction(context.Background(), func(ctx context.Context) error {
+		if _, err := bm.WithContext(ctx).Insert(&model.Teacher{Name: "declarative-rollback"}); err != nil {
+			return err
+		}
+		if count, err := bm.WithContext(ctx).CountByCond(&model.Teacher{Name: "declarative-rollback"}); err != nil || count != 1 {
+			t.Fatal("mapper should join transaction", count, err)
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatal(err)
+	}
+	if count, err := bm.CountByCond(&model.Teacher{Name: "declarative-rollback"}); err != nil || count != 0 {
+		t.Fatal(count, err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expect panic")
+			}
+		}()
+		_ = gormstarter.Transaction(context.Background(), func(ctx context.Context) error {
+			_, _ = bm.WithContext(ctx).Insert(&model.Teacher{Name: "declarative-panic"})
+			panic("panic")
+		})
+	}()
+	if count, err := bm.CountByCond(&model.Teacher{Name: "declarative-panic"}); err != nil || count != 0 {
+		t.Fatal(count, err)
+	}
+}
+
+func TestMapperTransaction(t *testing.T) {
+	var bm model.TeacherMapper
+	count := func(name string) int64 {
+		c, err := bm.CountByCond(&model.Teacher{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	rollback := errors.New("rollback")
+
+	// 持有事务的Mapper 默认加入该事务 随外层事务回滚
+	txMapper := bm.NewBaseMapperWithTx()
+	outer := bm.GetBaseMapperWithTx(txMapper.CurrentGorm())
+	// REQUIRES_NEW 独立提交 (sqlite外层事务写入前执行 避免锁表)
+	err := outer.Transaction(func(ctx context.Context, mapper gormstarter.BaseMapper[model.Teacher]) error {
+		if gormstarter.TxFromContext(ctx) == txMapper.CurrentGorm() {
+			t.Fatal("requires new should open a new transaction")
+		}
+		_, err := mapper.Insert(&model.Teacher{Name: "mapper-requires-new"})
+		return err
+	}, gormstarter.PropagationRequiresNew)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = outer.Transaction(func(ctx context.Context, mapper gormstarter.BaseMapper[model.Teacher]) error {
+		if gormstarter.TxFromContext(ctx) != txMapper.CurrentGorm() {
+			t.Fatal("mapper should join existing transaction")
+		}
+		_, err := mapper.Insert(&model.Teacher{Name: "mapper-required"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// NESTED 失败仅回滚到保存点
+	err = outer.Transaction(func(ctx context.Context, mapper gormstarter.BaseMapper[model.Teacher]) error {
+		if _, err := mapper.Insert(&model.Teacher{Name: "mapper-nested"}); err != nil {
+			return err
+		}
+		return rollback
+	}, gormstarter.PropagationNested)
+	if !errors.Is(err, rollback) {
+		t.Fatal(err)
+	}
+	if c, err := outer.CountByCond(&model.Teacher{Name: "mapper-required"}); err != nil || c != 1 {
+		t.Fatal("required should be visible in outer transaction", c, err)
+	}
+	if c, err := outer.CountByCond(&model.Teacher{Name: "mapper-nested"}); err != nil || c != 0 {
+		t.Fatal("nested should roll back to savepoint", c, err)
+	}
+	txMapper.CurrentGorm().Rollback()
+	if count("mapper-required") != 0 || count("mapper-requires-new") != 1 {
+		t.Fatal("mapper transaction propagation failed")
+	}
+
+	// 上下文中已存在事务时加入该事务
+	err = gormstarter.Transaction(context.Background(), func(ctx context.Context) error {
+		err := bm.WithContext(ctx).Transaction(func(innerCtx context.Context, mapper gormstarter.BaseMapper[model.Teacher]) error {
+			if gormstarter.TxFromContext(innerCtx) != gormstarter.TxFromContext(ctx) {
+				t.Fatal("mapper should join context transaction")
+			}
+			_, err := mapper.Insert(&model.Teacher{Name: "mapper-context"})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) || count("mapper-context") != 0 {
+		t.Fatal("mapper should roll back with context transaction", err)
+	}
+
+	// 不存在事务时开启新事务
+	err = bm.Transaction(func(ctx context.Context, mapper gormstarter.BaseMapper[model.Teacher]) error {
+		if gormstarter.TxFromContext(ctx) == nil {
+			t.Fatal("required should open transaction")
+		}
+		_, err := mapper.Insert(&model.Teacher{Name: "mapper-new"})
+		return err
+	})
+	if err != nil || count("mapper-new") != 1 {
+		t.Fatal(err)
+	}
+
+	// NewBaseMapperWithTx 加入Mapper持有的事务 提交由外层负责 随外层事务回滚
+	outerMapper := bm.NewBaseMapperWithTx()
+	if _, err = outerMapper.Insert(&model.Teacher{Name: "with-tx-outer"}); err != nil {
+		t.Fatal(err)
+	}
+	inner := outerMapper.NewBaseMapperWithTx()
+	if c, err := inner.CountByCond(&model.Teacher{Name: "with-tx-outer"}); err != nil || c != 1 {
+		t.Fatal("inner mapper should join outer transaction", c, err)
+	}
+	if _, err = inner.Insert(&model.Teacher{Name: "with-tx-inner"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = inner.CurrentGorm().Commit().Error; err != nil {
+		t.Fatal(err)
+	}
+	if err = outerMapper.CurrentGorm().Rollback().Error; err != nil {
+		t.Fatal(err)
+	}
+	if count("with-tx-outer") != 0 || count("with-tx-inner") != 0 {
+		t.Fatal("inner mapper should roll back with outer transaction")
+	}
+
+	// 加入上下文中的事务 内层回滚时整个事务回滚
+	err = gormstarter.Transaction(context.Background(), func(ctx context.Context) error {
+		if _, err := bm.WithContext(ctx).Insert(&model.Teacher{Name: "with-tx-context"}); err != nil {
+			return err
+		}
+		inner := bm.WithContext(ctx).NewBaseMapperWithTx()
+		if _, err := inner.Insert(&model.Teacher{Name: "with-tx-context"}); err != nil {
+			return err
+		}
+		return inner.CurrentGorm().Rollback().Error
+	})
+	if err == nil || count("with-tx-context") != 0 {
+		t.Fatal("inner rollback should roll back context transaction", err)
+	}
+}
+
+func TestTransactionPropagation(t *testing.T) {
+	var bm model.TeacherMapper
+	count := func(name string) int64 {
+		c, err := bm.CountByCond(&model.Teacher{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	rollback := errors.New("rollback")
+
+	// NESTED 内层失败仅回滚到保存点
+	err := gormstarter.Transaction(context.Background(), func(ctx context.Context) error {
+		if _, err := bm.WithContext(ctx).Insert(&model.Teacher{Name: "outer"}); err != nil {
+			return err
+		}
+		err := gormstarter.TransactionWithPropagation(ctx, gormstarter.PropagationNested, func(ctx context.Context) error {
+			if _, err := bm.WithContext(ctx).Insert(&model.Teacher{Name: "nested"}); err != nil {
+				return err
+			}
+			return rollback
+		})
+		if !errors.Is(err, rollback) {
+			t.Fatal(err)
+		}
+		return gormstarter.TransactionWithPropagation(ctx, gormstarter.PropagationNested, func(ctx context.Context) error {
+			_, err := bm.WithContext(ctx).Insert(&model.Teacher{Name: "nested-commit"})
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count("outer") != 1 || count("nested") != 0 || count("nested-commit") != 1 {
+		t.Fatal("nested propagation failed")
+	}
+
+	// REQUIRES_NEW 内层独立提交 不受外层回滚影响
+	err = gormstarter.Transaction(context.Background(), func(ctx context.Context) error {
+		err := gormstarter.TransactionWithPropagation(ctx, gormstarter.PropagationRequiresNew, func(innerCtx context.Context) error {
+			if gormstarter.TxFromContext(innerCtx) == gormstarter.TxFromContext(ctx) {
+				t.Fatal("requires new should open a new transaction")
+			}
+			_, err := bm.WithContext(innerCtx).Insert(&model.Teacher{Name: "requires-new"})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) || count("requires-new") != 1 {
+		t.Fatal("requires new propagation failed", err)
+	}
+
+	// SUPPORTS 无事务时以非事务方式执行
+	err = gormstarter.TransactionWithPropagation(context.Background(), gormstarter.PropagationSupports, func(ctx context.Context) error {
+		if gormstarter.TxFromContext(ctx) != nil {
+			t.Fatal("supports should not open transaction")
+		}
+		_, err := bm.WithContext(ctx).Insert(&model.Teacher{Name: "supports"})
+		if err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) || count("supports") != 1 {
+		t.Fatal("supports propagation failed", err)
+	}
+
+	// NEVER 存在事务时返回错误
+	err = gormstarter.Transaction(context.Background(), func(ctx context.Context) error {
+		return gormstarter.TransactionWithPropagation(ctx, gormstarter.PropagationNever, func(ctx context.Context) error {
+			return nil
+		})
+	})
+	if !errors.Is(err, gormstarter.ErrExistingTransaction) {
+		t.Fatal(err)
+	}
+
+	// 通过 ContextWithTx 将Mapper事务传递给基于上下文的调用
+	txMapper := bm.NewBaseMapperWithTx()
+	ctx := gormstarter.ContextWithTx(context.Background(), txMapper.CurrentGorm())
+	err = gormstarter.Transaction(ctx, func(ctx context.Context) error {
+		_, err := bm.WithContext(ctx).Insert(&model.Teacher{Name: "bridge"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txMapper.CurrentGorm().Rollback()
+	if count("bridge") != 0 {
+		t.Fatal("bridge should join mapper transaction")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	var bm model.TeacherMapper
+	teachers := []*model.Teacher{
+		{Name: "query-a", Age: 10, Sex: 1, ClassNo: 1},
+		{Name: "query-b", Age: 20, Sex: 0, ClassNo: 2},
+		{Name: "query-c", Age: 30, Sex: 1, ClassNo: 3},
+	}
+	if _, err := bm.InsertBatch(&teachers); err != nil {
+		t.Fatal(err)
+	}
+
+	var selected []*model.Teacher
+	query := gormstarter.NewQuery[model.Teacher]().
+		Like("name", "query-%").
+		Or(func(q *gormstarter.Query[model.Teacher]) {
+			q.Eq("Sex", 1).Ge("age", 20)
+		}, func(q *gormstarter.Query[model.Teacher]) {
+			q.In("class_no", 1, 2)
+		}).
+		OrderByDesc("age").
+		Select("id", "name", "age")
+	if _, err := bm.SelectByQuery(query, &selected); err != nil || len(selected) != 3 || selected[0].Name != "query-c" || selected[0].Sex != 0 {
+		t.Fatal(len(selected), err)
+	}
+
+	var one model.Teacher
+	if _, err := bm.SelectOneByQuery(gormstarter.NewQuery[model.Teacher]().Between("age", 15, 25).IsNotNull("name"), &one); err != nil || one.Name != "query-b" {
+		t.Fatal(one, err)
+	}
+	if count, err := bm.CountByQuery(gormstarter.NewQuery[model.Teacher]().Like("name", "query-%").NotIn("age", 10).Ne("sex", 0)); err != nil || count != 1 {
+		t.Fatal(count, err)
+	}
+
+	var page []*model.Teacher
+	if total, err := bm.SelectPageByQuery(gormstarter.NewQuery[model.Teacher]().Like("name", "query-%").OrderByAsc("age"), 2, 2, &page); err != nil || total != 3 || len(page) != 1 {
+		t.Fatal(total, len(page), err)
+	}
+
+	if rows, err := bm.UpdateByQuery(map[string]any{"ClassNo": 9}, gormstarter.NewQuery[model.Teacher]().Lt("age", 20).Like("name", "query-%")); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if _, err := bm.SelectByQuery(gormstarter.NewQuery[model.Teacher]().Eq("unknown", 1), &selected); !errors.Is(err, gormstarter.ErrUnknownColumn) {
+		t.Fatal(err)
+	}
+	if _, err := bm.DeleteByQuery(gormstarter.NewQuery[model.Teacher]()); err == nil {
+		t.Fatal("expect missing where clause")
+	}
+	if rows, err := bm.DeleteByQuery(gormstarter.NewQuery[model.Teacher]().Like("name", "query-%").And(func(q *gormstarter.Query[model.Teacher]) {
+		q.Eq("class_no", 9).IsNotNull("update_time")
+	})); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+}
+
+func TestColumns(t *testing.T) {
+	var bm model.TeacherMapper
+	columns := model.TeacherColumns
+	teachers := []*model.Teacher{{Name: "column", Age: 1}, {Name: "column", Age: 2}}
+	if _, err := bm.InsertBatch(&teachers, columns.CreatedAt, columns.UpdatedAt); err != nil {
+		t.Fatal(err)
+	}
+	var selected []*model.Teacher
+	if _, err := bm.SelectByCond(&model.Teacher{Name: "column"}, gormstarter.OrderBy(columns.Age.Desc(), columns.ID.Asc()), &selected,
+		columns.ID, columns.Age); err != nil || len(selected) != 2 || selected[0].Age != 2 || selected[0].Name != "" {
+		t.Fatal(len(selected), err)
+	}
+	query := gormstarter.NewQuery[model.Teacher]().Eq(columns.Name, "column").OrderByAsc(columns.Age).Select(columns.Age)
+	if _, err := bm.SelectByQuery(query, &selected); err != nil || len(selected) != 2 || selected[0].Age != 1 {
+		t.Fatal(len(selected), err)
+	}
+	if _, err := bm.SelectByCond(&model.Teacher{Name: "column"}, "", &selected, "unknown"); !errors.Is(err, gormstarter.ErrUnknownColumn) {
+		t.Fatal(err)
+	}
+	if _, err := bm.SelectByCond(&model.Teacher{Name: "column"}, "unknown desc", &selected); !errors.Is(err, gormstarter.ErrUnknownColumn) {
+		t.Fatal(err)
+	}
+	if _, err := bm.SelectByCond(&model.Teacher{Name: "column"}, "age; drop table demo_teacher", &selected); err == nil {
+		t.Fatal("expect invalid orderBy error")
+	}
+	if _, err := bm.Insert(&model.Teacher{Name: "column"}, "unknown"); !errors.Is(err, gormstarter.ErrUnknownColumn) {
+		t.Fatal(err)
+	}
+	// 结构体字段名同样可以作为字段描述
+	if found, err := bm.FindListByCond(&model.Teacher{Name: "column"}, "Age desc", "Age"); err != nil || len(found) != 2 || found[0].Age != 2 {
+		t.Fatal(len(found), err)
+	}
+	// []string 类型的字段变量通过 Columns 转换 排序语句的空白数量不限
+	names := []string{"id", "age"}
+	if _, err := bm.SelectByCond(&model.Teacher{Name: "column"}, "  age   desc ,id", &selected, gormstarter.Columns[model.Teacher](names...)...); err != nil ||
+		len(selected) != 2 || selected[0].Age != 2 || selected[0].Name != "" {
+		t.Fatal(len(selected), err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	var bm model.TeacherMapper
+	teacher := model.Teacher{Name: "find", Age: 18}
+	if _, err := bm.Insert(&teacher); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := bm.FindById(teacher.ID); err != nil || found.Name != "find" {
+		t.Fatal(found, err)
+	}
+	if _, err := bm.FindById(0); !errors.Is(err, gormstarter.ErrNotFound) {
+		t.Fatal(err)
+	}
+	if found, err := bm.FindByIds([]any{teacher.ID, 0}); err != nil || len(found) != 1 {
+		t.Fatal(found, err)
+	}
+	if found, err := bm.FindOneByCond(&model.Teacher{Name: "find"}); err != nil || found.ID != teacher.ID {
+		t.Fatal(found, err)
+	}
+	if found, err := bm.FindOneByMap(map[string]any{"name": "find", "age": 18}, "id"); err != nil || found.ID != teacher.ID || found.Name != "" {
+		t.Fatal(found, err)
+	}
+	if _, err := bm.FindOneByWhere("name = ?", "not-found"); !errors.Is(err, gormstarter.ErrNotFound) {
+		t.Fatal(err)
+	}
+	if found, err := bm.FindOne(gormstarter.NewQuery[model.Teacher]().Eq(model.TeacherColumns.Name, "find")); err != nil || found.ID != teacher.ID {
+		t.Fatal(found, err)
+	}
+	if found, err := bm.FindListByCond(&model.Teacher{Name: "not-found"}, ""); err != nil || found == nil || len(found) != 0 {
+		t.Fatal(found, err)
+	}
+	if found, err := bm.FindListByMap(map[string]any{"name": "find"}, "id desc"); err != nil || len(found) != 1 {
+		t.Fatal(found, err)
+	}
+	if found, err := bm.FindListByWhere("age = ?", "", 18); err != nil || len(found) == 0 {
+		t.Fatal(found, err)
+	}
+	if found, err := bm.FindList(gormstarter.NewQuery[model.Teacher]().Eq(model.TeacherColumns.Name, "find")); err != nil || len(found) != 1 {
+		t.Fatal(found, err)
+	}
+}
+
+func TestFindPageByCursor(t *testing.T) {
+	var bm model.TeacherMapper
+	columns := model.TeacherColumns
+	teachers := []*model.Teacher{
+		{Name: "cursor", Age: 3}, {Name: "cursor", Age: 1}, {Name: "cursor", Age: 2},
+		{Name: "cursor", Age: 2}, {Name: "cursor", Age: 1},
+	}
+	if _, err := bm.InsertBatch(&teachers); err != nil {
+		t.Fatal(err)
+	}
+	query := gormstarter.NewQuery[model.Teacher]().Eq(columns.Name, "cursor").OrderByDesc(columns.Age).Select(columns.Name)
+	ages := func(page *gormstarter.CursorPage[model.Teacher]) []uint {
+		var result []uint
+		for _, record := range page.Records {
+			result = append(result, record.Age)
+		}
+		return result
+	}
+
+	first, err := bm.FindPageByCursor(query, "", 2, true)
+	if err != nil || first.Total != 5 || first.PrevCursor != "" || first.NextCursor == "" || !slices.Equal(ages(first), []uint{3, 2}) {
+		t.Fatal(first, err)
+	}
+	second, err := bm.FindPageByCursor(query, first.NextCursor, 2)
+	if err != nil || second.Total != -1 || second.PrevCursor == "" || second.NextCursor == "" || !slices.Equal(ages(second), []uint{2, 1}) {
+		t.Fatal(second, err)
+	}
+	if second.Records[0].ID == first.Records[1].ID {
+		t.Fatal("duplicate record between pages")
+	}
+	last, err := bm.FindPageByCursor(query, second.NextCursor, 2)
+	if err != nil || last.NextCursor != "" || !slices.Equal(ages(last), []uint{1}) {
+		t.Fatal(last, err)
+	}
+	prev, err := bm.FindPageByCursor(query, last.PrevCursor, 2)
+	if err != nil || prev.NextCursor == "" || prev.Records[0].ID != second.Records[0].ID || prev.Records[1].ID != second.Records[1].ID {
+		t.Fatal(prev, err)
+	}
+	prev, err = bm.FindPageByCursor(query, prev.PrevCursor, 2)
+	if err != nil || prev.PrevCursor != "" || prev.Records[0].ID != first.Records[0].ID {
+		t.Fatal(prev, err)
+	}
+
+	if _, err = bm.FindPageByCursor(query, "invalid", 2); !errors.Is(err, gormstarter.ErrInvalidCursor) {
+		t.Fatal(err)
+	}
+	otherQuery := gormstarter.NewQuery[model.Teacher]().Eq(columns.Name, "cursor").OrderByAsc(columns.ClassNo)
+	if _, err = bm.FindPageByCursor(otherQuery, first.NextCursor, 2); !errors.Is(err, gormstarter.ErrInvalidCursor) {
+		t.Fatal(err)
+	}
+}
+
+func TestFindPage(t *testing.T) {
+	var bm model.TeacherMapper
+	columns := model.TeacherColumns
+	teachers := make([]*model.Teacher, 5)
+	for i := range teachers {
+		teachers[i] = &model.Teacher{Name: "find-page", Age: uint(i + 1)}
+	}
+	if _, err := bm.InsertBatch(&teachers); err != nil {
+		t.Fatal(err)
+	}
+	query := gormstarter.NewQuery[model.Teacher]().Eq(columns.Name, "find-page").OrderByAsc(columns.Age)
+
+	page, err := bm.FindPage(query, 2, 2)
+	if err != nil || page.Total != 5 || page.TotalPages != 3 || !page.HasNext || !page.HasPrev || len(page.Records) != 2 || page.Records[0].Age != 3 {
+		t.Fatal(page, err)
+	}
+	page, err = bm.FindPage(query, 4, 2)
+	if err != nil || page.Total != 5 || page.HasNext || len(page.Records) != 0 {
+		t.Fatal(page, err)
+	}
+	page, err = bm.FindPage(query, 3, 2, gormstarter.PageOptions{Count: gormstarter.CountNone})
+	if err != nil || page.Total != -1 || page.TotalPages != -1 || page.HasNext || len(page.Records) != 1 {
+		t.Fatal(page, err)
+	}
+	page, err = bm.FindPage(query, 1, 2, gormstarter.PageOptions{Count: gormstarter.CountNone})
+	if err != nil || !page.HasNext || len(page.Records) != 2 {
+		t.Fatal(page, err)
+	}
+	// sqlite 不支持估算 使用精确总数
+	page, err = bm.FindPageByCond(&model.Teacher{Name: "find-page"}, "age desc", 1, 3, []gormstarter.PageOptions{{Count: gormstarter.CountEstimate}})
+	if err != nil || page.Total != 5 || !page.HasNext || page.Records[0].Age != 5 {
+		t.Fatal(page, err)
+	}
+	page, err = bm.FindPageByMap(map[string]any{"name": "find-page"}, "age", 2, 3, []gormstarter.PageOptions{{Count: gormstarter.CountConcurrent}}, "id", "age")
+	if err != nil || page.Total != 5 || page.HasNext || len(page.Records) != 2 || page.Records[0].Name != "" {
+		t.Fatal(page, err)
+	}
+	err = gormstarter.Transaction(context.Background(), func(ctx context.Context) error {
+		page, err := bm.WithContext(ctx).FindPageByWhere("name = ?", "age", 1, 10, []any{"find-page"}, []gormstarter.PageOptions{{Count: gormstarter.CountConcurrent}})
+		if err != nil || page.Total != 5 || len(page.Records) != 5 {
+			t.Fatal(page, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIterate(t *testing.T) {
+	var bm model.TeacherMapper
+	columns := model.TeacherColumns
+	teachers := make([]*model.Teacher, 7)
+	for i := range teachers {
+		teachers[i] = &model.Teacher{Name: "iterate", Age: uint(i + 1)}
+	}
+	if _, err := bm.InsertBatch(&teachers); err != nil {
+		t.Fatal(err)
+	}
+	query := gormstarter.NewQuery[model.Teacher]().Eq(columns.Name, "iterate").OrderByDesc(columns.Age)
+
+	var ages []uint
+	for teacher, err := range bm.Iterate(query) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ages = append(ages, teacher.Age)
+		if len(ages) == 3 {
+			break
+		}
+	}
+	if !slices.Equal(ages, []uint{7, 6, 5}) {
+		t.Fatal(ages)
+	}
+	ages = ages[:0]
+	for teacher, err := range bm.IterateByCond(&model.Teacher{Name: "iterate"}, "age") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ages = append(ages, teacher.Age)
+	}
+	if len(ages) != 7 || ages[0] != 1 {
+		t.Fatal(ages)
+	}
+	for _, err := range bm.IterateByWhere("unknown = ?", "", 1) {
+		if err == nil {
+			t.Fatal("expect error")
+		}
+	}
+
+	count := 0
+	err := bm.Each(query, func(teacher *model.Teacher) error {
+		count++
+		if teacher.Age == 4 {
+			return gormstarter.ErrStopIteration
+		}
+		return nil
+	})
+	if err != nil || count != 4 {
+		t.Fatal(count, err)
+	}
+
+	var batches [][]uint
+	total, err := bm.FindInBatches(query.Select(columns.Age), 3, func(batch []*model.Teacher) error {
+		var batchAges []uint
+		for _, teacher := range batch {
+			batchAges = append(batchAges, teacher.Age)
+		}
+		batches = append(batches, batchAges)
+		return nil
+	})
+	if err != nil || total != 7 || len(batches) != 3 || len(batches[2]) != 1 || batches[2][0] != 7 {
+		t.Fatal(total, batches, err)
+	}
+	total, err = bm.FindInBatches(query, 3, func(batch []*model.Teacher) error {
+		return gormstarter.ErrStopIteration
+	})
+	if err != nil || total != 3 {
+		t.Fatal(total, err)
+	}
+}
+
+func TestInsertInBatches(t *testing.T) {
+	var bm model.TeacherMapper
+	teachers := make([]*model.Teacher, 7)
+	for i := range teachers {
+		teachers[i] = &model.Teacher{Name: "in-batches", Age: uint(i)}
+	}
+	rows, err := bm.InsertInBatches(&teachers, 3)
+	if err != nil || !slices.Equal(rows, []int64{3, 3, 1}) || teachers[6].ID == 0 {
+		t.Fatal(rows, err)
+	}
+	// 排除的字段对每一批均生效
+	teachers = make([]*model.Teacher, 5)
+	for i := range teachers {
+		teachers[i] = &model.Teacher{Name: "in-batches-omit", Sex: 2, Age: uint(i)}
+	}
+	if rows, err = bm.InsertInBatches(&teachers, 2, "sex"); err != nil || !slices.Equal(rows, []int64{2, 2, 1}) {
+		t.Fatal(rows, err)
+	}
+	var omitted []*model.Teacher
+	if _, err = bm.SelectByCond(&model.Teacher{Name: "in-batches-omit"}, "age", &omitted); err != nil || len(omitted) != 5 {
+		t.Fatal(len(omitted), err)
+	}
+	for i, teacher := range omitted {
+		if teacher.Sex != 1 || teacher.Age != uint(i) || teacher.ID != teachers[i].ID {
+			t.Fatalf("%+v", teacher)
+		}
+	}
+	// 未指定批次大小且未配置 CreateBatchSize 时不分批
+	teachers = []*model.Teacher{{Name: "in-batches"}, {Name: "in-batches"}}
+	if rows, err = bm.InsertInBatches(&teachers, 0); err != nil || !slices.Equal(rows, []int64{2}) {
+		t.Fatal(rows, err)
+	}
+
+	var cm model.TeacherClassMapper
+	links := []*model.TeacherClass{
+		{TeacherId: 100, ClassNo: 1}, {TeacherId: 100, ClassNo: 2},
+		{TeacherId: 100, ClassNo: 3}, {TeacherId: 100, ClassNo: 1},
+	}
+	if rows, err = cm.InsertInBatchesWithTx(&links, 2); err == nil {
+		t.Fatal("expect duplicate primary key", rows)
+	}
+	if count, err := cm.CountByCond(&model.TeacherClass{TeacherId: 100}); err != nil || count != 0 {
+		t.Fatal("all batches should be rolled back", count, err)
+	}
+	if rows, err = cm.InsertInBatches(&links, 2); err == nil || !slices.Equal(rows, []int64{2}) {
+		t.Fatal(rows, err)
+	}
+	if count, err := cm.CountByCond(&model.TeacherClass{TeacherId: 100}); err != nil || count != 2 {
+		t.Fatal("succeeded batches should be kept", count, err)
+	}
+}
+
+func TestInsertOnConflict(t *testing.T) {
+	var bm model.TeacherClassMapper
+	columns := model.TeacherClassColumns
+	link := model.TeacherClass{TeacherId: 200, ClassNo: 1, Remark: "init"}
+	if _, err := bm.Insert(&link); err != nil {
+		t.Fatal(err)
+	}
+	// 默认使用主键判断冲突 并更新其他字段
+	link.Remark = "updated"
+	if _, err := bm.InsertOnConflict(&link, gormstarter.OnConflict[model.TeacherClass]{}); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := bm.FindById(link); err != nil || found.Remark != "updated" {
+		t.Fatal(found, err)
+	}
+
+	links := []*model.TeacherClass{
+		{TeacherId: 200, ClassNo: 1, Remark: "ignored"},
+		{TeacherId: 200, ClassNo: 2, Remark: "inserted"},
+	}
+	if rows, err := bm.InsertBatchOnConflict(&links, gormstarter.OnConflict[model.TeacherClass]{DoNothing: true}); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if found, err := bm.FindById(gormstarter.CompositeKey{200, 1}); err != nil || found.Remark != "updated" {
+		t.Fatal(found, err)
+	}
+
+	links[0].Remark, links[1].Remark = "batch", "batch"
+	onConflict := gormstarter.OnConflict[model.TeacherClass]{
+		ConflictColumns: []gormstarter.Column[model.TeacherClass]{columns.TeacherId, columns.ClassNo},
+		UpdateColumns:   []gormstarter.Column[model.TeacherClass]{columns.Remark},
+	}
+	if _, err := bm.InsertBatchOnConflict(&links, onConflict); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := bm.CountByCond(&model.TeacherClass{TeacherId: 200, Remark: "batch"}); err != nil || count != 2 {
+		t.Fatal(count, err)
+	}
+	onConflict.UpdateColumns = []gormstarter.Column[model.TeacherClass]{"unknown"}
+	if _, err := bm.InsertOnConflict(&link, onConflict); !errors.Is(err, gormstarter.ErrUnknownColumn) {
+		t.Fatal(err)
+	}
+}
+
+func TestOptimisticLock(t *testing.T) {
+	var bm model.TeacherMapper
+	teacher := model.Teacher{Name: "lock", Age: 20}
+	if _, err := bm.Insert(&teacher); err != nil {
+		t.Fatal(err)
+	}
+	stale := teacher
+	teacher.Age = 21
+	if rows, err := bm.UpdateById(&teacher); err != nil || rows != 1 || teacher.Version != 1 {
+		t.Fatal(rows, err, teacher.Version)
+	}
+	// 版本号已过期 更新失败并还原版本号
+	stale.Age = 22
+	if _, err := bm.UpdateById(&stale); !errors.Is(err, gormstarter.ErrOptimisticLock) || stale.Version != 0 {
+		t.Fatal(err, stale.Version)
+	}
+	teacher.Age = 23
+	if _, err := bm.UpdateByIdWithoutZeroField(&teacher); err != nil || teacher.Version != 2 {
+		t.Fatal(err, teacher.Version)
+	}
+
+	// map中指定版本号时作为更新条件
+	if _, err := bm.UpdateByIdUseMap(map[string]any{"age": 24, "version": 1}, teacher.ID); !errors.Is(err, gormstarter.ErrOptimisticLock) {
+		t.Fatal(err)
+	}
+	if _, err := bm.UpdateByIdUseMap(map[string]any{"age": 24, "version": 2}, teacher.ID); err != nil {
+		t.Fatal(err)
+	}
+	// map中未指定版本号时仅将版本号加1
+	if _, err := bm.UpdateByIdUseMap(map[string]any{"age": 25}, teacher.ID); err != nil {
+		t.Fatal(err)
+	}
+	found, err := bm.FindById(teacher.ID)
+	if err != nil || found.Age != 25 || found.Version != 4 {
+		t.Fatal(found, err)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	var mm model.MemberMapper
+	first, second := model.Member{Name: "soft"}, model.Member{Name: "soft"}
+	if _, err := mm.Insert(&first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mm.Insert(&second); err != nil {
+		t.Fatal(err)
+	}
+	if rows, err := mm.DeleteById(first.ID); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if count, err := mm.CountByCond(&model.Member{Name: "soft"}); err != nil || count != 1 {
+		t.Fatal(count, err)
+	}
+	if count, err := mm.WithDeleted().CountByCond(&model.Member{Name: "soft"}); err != nil || count != 2 {
+		t.Fatal(count, err)
+	}
+	if _, err := mm.FindById(first.ID); !errors.Is(err, gormstarter.ErrNotFound) {
+		t.Fatal(err)
+	}
+	// OR条件不会绕过软删除条件
+	var members []*model.Member
+	if _, err := mm.SelectByGorm(&members, func(db *gorm.DB) {
+		db.Where("name = ?", "none").Or("name = ?", "soft")
+	}); err != nil || len(members) != 1 {
+		t.Fatal(len(members), err)
+	}
+	// 已删除的数据不会被更新
+	first.Sex = 2
+	if rows, err := mm.UpdateById(&first); err != nil || rows != 0 {
+		t.Fatal(rows, err)
+	}
+	if _, err := mm.DeleteByMap(map[string]any{}); !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Fatal(err)
+	}
+
+	if rows, err := mm.RestoreById(first.ID); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if _, err := mm.FindById(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mm.DeleteByCond(&model.Member{Name: "soft"}); err != nil {
+		t.Fatal(err)
+	}
+	if rows, err := mm.PurgeDeleted(time.Now().Add(-time.Hour)); err != nil || rows != 0 {
+		t.Fatal(rows, err)
+	}
+	if _, err := mm.RestoreById(second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if rows, err := mm.PurgeDeleted(); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if count, err := mm.WithDeleted().CountByCond(&model.Member{Name: "soft"}); err != nil || count != 1 {
+		t.Fatal(count, err)
+	}
+
+	// 标记删除
+	var cm model.TeacherClassMapper
+	link := model.TeacherClass{TeacherId: 300, ClassNo: 1}
+	if _, err := cm.Insert(&link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.DeleteByCond(&model.TeacherClass{TeacherId: 300}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.FindById(link); !errors.Is(err, gormstarter.ErrNotFound) {
+		t.Fatal(err)
+	}
+	if found, err := cm.WithDeleted().FindById(link); err != nil || !found.Deleted {
+		t.Fatal(found, err)
+	}
+	if _, err := cm.PurgeDeleted(time.Now()); err == nil {
+		t.Fatal("flag soft delete should not purge by time")
+	}
+	if rows, err := cm.HardDeleteById(link); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if count, err := cm.WithDeleted().CountByCond(&model.TeacherClass{TeacherId: 300}); err != nil || count != 0 {
+		t.Fatal(count, err)
+	}
+
+	var tm model.TeacherMapper
+	if _, err := tm.RestoreById(1); !errors.Is(err, gormstarter.ErrMissingSoftDelete) {
+		t.Fatal(err)
+	}
+}
+
+func TestOperator(t *testing.T) {
+	var sm model.StaffMapper
+	alice := sm.WithContext(context.WithValue(context.Background(), operatorKey{}, "alice"))
+	carol := sm.WithContext(context.WithValue(context.Background(), operatorKey{}, "carol"))
+	staff := model.Staff{Name: "operator"}
+	if _, err := alice.Insert(&staff); err != nil {
+		t.Fatal(err)
+	}
+	if staff.CreatedBy != "alice" || staff.UpdatedBy != "alice" {
+		t.Fatalf("%+v", staff)
+	}
+	// 已指定的创建人不会被覆盖
+	preset := model.Staff{Name: "operator", CreatedBy: "bob"}
+	if _, err := alice.InsertWithoutZeroField(&preset); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := sm.FindById(preset.ID); err != nil || found.CreatedBy != "bob" || found.UpdatedBy != "alice" {
+		t.Fatal(found, err)
+	}
+	// 冲突更新时不覆盖创建人
+	if _, err := carol.InsertOnConflict(&model.Staff{ID: preset.ID, Name: "upsert"}, gormstarter.OnConflict[model.Staff]{}); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := sm.FindById(preset.ID); err != nil || found.Name != "upsert" || found.CreatedBy != "bob" || found.UpdatedBy != "carol" {
+		t.Fatal(found, err)
+	}
+
+	staff.Sex = 2
+	if _, err := carol.UpdateById(&staff, "sex"); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := sm.FindById(staff.ID); err != nil || found.CreatedBy != "alice" || found.UpdatedBy != "carol" {
+		t.Fatal(found, err)
+	}
+	if _, err := alice.UpdateByMap(map[string]any{"sex": 1}, map[string]any{"id": staff.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := sm.FindById(staff.ID); err != nil || found.UpdatedBy != "alice" {
+		t.Fatal(found, err)
+	}
+	if _, err := carol.DeleteById(staff.ID); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := sm.WithDeleted().FindById(staff.ID); err != nil || found.DeletedBy != "carol" {
+		t.Fatal(found, err)
+	}
+
+	// 上下文中没有操作人时不填充
+	anonymous := model.Staff{Name: "operator"}
+	if _, err := sm.Insert(&anonymous); err != nil || anonymous.CreatedBy != "" {
+		t.Fatal(anonymous, err)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	var am model.AuditTeacherMapper
+	mapper := am.WithContext(context.WithValue(context.Background(), operatorKey{}, "auditor"))
+	records := func(id uint64) []*gormstarter.AuditRecord {
+		var records []*gormstarter.AuditRecord
+		if err := gormstarter.RawGormDB().Table(gormstarter.DefaultAuditTable).
+			Where("table_name = ? and primary_key = ?", "demo_teacher", strconv.FormatUint(id, 10)).Order("id").Find(&records).Error; err != nil {
+			t.Fatal(err)
+		}
+		return records
+	}
+
+	teacher := model.AuditTeacher{Name: "audit"}
+	if _, err := mapper.Insert(&teacher); err != nil {
+		t.Fatal(err)
+	}
+	teacher.Sex = 2
+	if _, err := mapper.UpdateById(&teacher, "sex"); err != nil {
+		t.Fatal(err)
+	}
+	// 未变更数据时不记录
+	if _, err := mapper.UpdateByMap(map[string]any{"sex": 2}, map[string]any{"id": teacher.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mapper.DeleteById(teacher.ID); err != nil {
+		t.Fatal(err)
+	}
+	list := records(teacher.ID)
+	if len(list) != 3 {
+		t.Fatal(len(list))
+	}
+	insert, update, del := list[0], list[1], list[2]
+	if insert.Operation != gormstarter.AuditInsert || insert.Before != "" || !strings.Contains(insert.After, `"name":"audit"`) || insert.Operator != "auditor" {
+		t.Fatalf("%+v", insert)
+	}
+	if update.Operation != gormstarter.AuditUpdate || !strings.Contains(update.Before, `"sex":0`) || !strings.Contains(update.After, `"sex":2`) || strings.Contains(update.After, `"name"`) {
+		t.Fatalf("%+v", update)
+	}
+	if del.Operation != gormstarter.AuditDelete || !strings.Contains(del.Before, `"sex":2`) || del.After != "" {
+		t.Fatalf("%+v", del)
+	}
+
+	// 通过map新增时使用回填的自增主键
+	values := map[string]any{"name": "audit-map"}
+	if _, err := mapper.InsertUseMap(values); err != nil {
+		t.Fatal(err)
+	}
+	id, _ := values["@id"].(int64)
+	if list = records(uint64(id)); id == 0 || len(list) != 1 || list[0].Operation != gormstarter.AuditInsert || !strings.Contains(list[0].After, `"name":"audit-map"`) {
+		t.Fatal(id, list)
+	}
+
+	// 变更记录与数据变更处于同一事务
+	var rollback model.AuditTeacher
+	err := gormstarter.Transaction(context.Background(), func(ctx context.Context) error {
+		if _, err := am.WithContext(ctx).Insert(&model.AuditTeacher{Name: "audit"}); err != nil {
+			return err
+		}
+		if _, err := am.WithContext(ctx).SelectOneByCond(&model.AuditTeacher{Name: "audit"}, &rollback); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	if err == nil || rollback.ID == 0 {
+		t.Fatal(err, rollback.ID)
+	}
+	if list = records(rollback.ID); len(list) != 0 {
+		t.Fatal(len(list))
+	}
+
+	// 冲突时不做处理 不记录新增
+	conflict := model.AuditTeacher{Name: "conflict"}
+	if _, err = mapper.Insert(&conflict); err != nil {
+		t.Fatal(err)
+	}
+	duplicate := model.AuditTeacher{ID: conflict.ID, Name: "duplicate"}
+	if rows, err := mapper.InsertOnConflict(&duplicate, gormstarter.OnConflict[model.AuditTeacher]{DoNothing: true}); err != nil || rows != 0 {
+		t.Fatal(rows, err)
+	}
+	if list = records(conflict.ID); len(list) != 1 {
+		t.Fatal(len(list))
+	}
+	// 冲突时更新 记录为更新
+	duplicate.Sex = 2
+	if _, err = mapper.InsertOnConflict(&duplicate, gormstarter.OnConflict[model.AuditTeacher]{UpdateColumns: []gormstarter.Column[model.AuditTeacher]{"name", "sex"}}); err != nil {
+		t.Fatal(err)
+	}
+	if list = records(conflict.ID); len(list) != 2 {
+		t.Fatal(len(list))
+	}
+	if upsert := list[1]; upsert.Operation != gormstarter.AuditUpdate || !strings.Contains(upsert.Before, `"name":"conflict"`) ||
+		!strings.Contains(upsert.After, `"name":"duplicate"`) || !strings.Contains(upsert.After, `"sex":2`) {
+		t.Fatalf("%+v", upsert)
+	}
+	inserted := model.AuditTeacher{Name: "upsert"}
+	if _, err = mapper.InsertOnConflict(&inserted, gormstarter.OnConflict[model.AuditTeacher]{}); err != nil {
+		t.Fatal(err)
+	}
+	if list = records(inserted.ID); len(list) != 1 || list[0].Operation != gormstarter.AuditInsert {
+		t.Fatal(len(list))
+	}
+}
+
+func TestAuditOptimisticLock(t *testing.T) {
+	var bm model.AuditTeacherMapper
+	teachers := []*model.AuditTeacher{{Name: "audit-lock"}, {Name: "audit-lock"}, {Name: "audit-lock"}}
+	if _, err := bm.InsertBatch(&teachers); err != nil {
+		t.Fatal(err)
+	}
+	ids := []uint64{teachers[0].ID, teachers[1].ID, teachers[2].ID}
+	// 更新条件中包含版本号 变更前的数据仅查询被更新的数据
+	var snapshots []int64
+	query := gormstarter.RawGormDB().Callback().Query()
+	if err := query.After("gorm:query").Register("test:audit_snapshot", func(db *gorm.DB) {
+		if sql := db.Statement.SQL.String(); strings.Contains(sql, "demo_teacher") && strings.Contains(sql, "version") {
+			snapshots = append(snapshots, db.RowsAffected)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = query.Remove("test:audit_snapshot")
+	}()
+	updated := *teachers[1]
+	updated.Age = 30
+	if rows, err := bm.UpdateById(&updated); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	updated.Age = 31
+	if rows, err := bm.UpdateByIdWithoutZeroField(&updated); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	var records []*gormstarter.AuditRecord
+	if err := gormstarter.RawGormDB().Table(gormstarter.DefaultAuditTable).
+		Where("table_name = ? and operation = ? and primary_key in ?", "demo_teacher", gormstarter.AuditUpdate,
+			[]string{strconv.FormatUint(ids[0], 10), strconv.FormatUint(ids[1], 10), strconv.FormatUint(ids[2], 10)}).
+		Order("id").Find(&records).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatal(len(records))
+	}
+	for _, record := range records {
+		if record.PrimaryKey != strconv.FormatUint(ids[1], 10) {
+			t.Fatalf("%+v", record)
+		}
+	}
+	if !strings.Contains(records[0].After, `"age":30`) || !strings.Contains(records[1].After, `"age":31`) {
+		t.Fatalf("%+v %+v", records[0], records[1])
+	}
+	if len(snapshots) != 2 || snapshots[0] != 1 || snapshots[1] != 1 {
+		t.Fatal(snapshots)
+	}
+}
+
+func TestTenant(t *testing.T) {
+	var cm model.CourseMapper
+	acme := cm.WithContext(context.WithValue(context.Background(), tenantKey{}, "acme"))
+	globex := cm.WithContext(context.WithValue(context.Background(), tenantKey{}, "globex"))
+
+	// 未获取到租户时拒绝执行
+	if _, err := cm.Insert(&model.Course{Name: "math"}); !errors.Is(err, gormstarter.ErrMissingTenant) {
+		t.Fatal(err)
+	}
+	if _, err := cm.CountByCond(&model.Course{}); !errors.Is(err, gormstarter.ErrMissingTenant) {
+		t.Fatal(err)
+	}
+
+	// 新增时填充当前租户 已指定的租户将被覆盖
+	math := model.Course{Name: "math", TenantId: "globex"}
+	if _, err := acme.Insert(&math); err != nil {
+		t.Fatal(err)
+	}
+	if math.TenantId != "acme" {
+		t.Fatal(math)
+	}
+	if _, err := acme.InsertUseMap(map[string]any{"name": "art"}); err != nil {
+		t.Fatal(err)
+	}
+	physics := model.Course{Name: "physics"}
+	if _, err := globex.Insert(&physics); err != nil {
+		t.Fatal(err)
+	}
+
+	if count, err := acme.CountByCond(&model.Course{}); err != nil || count != 2 {
+		t.Fatal(count, err)
+	}
+	if _, err := acme.FindById(physics.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatal(err)
+	}
+	// OR条件不会绕过租户条件
+	var result []*model.Course
+	if _, err := acme.SelectByGorm(&result, func(db *gorm.DB) {
+		db.Where("name = ?", "math").Or("name = ?", "physics")
+	}); err != nil || len(result) != 1 || result[0].ID != math.ID {
+		t.Fatal(result, err)
+	}
+
+	// 不能更新/删除其他租户的数据
+	physics.Name = "hijacked"
+	if rows, err := acme.UpdateById(&physics); err != nil || rows != 0 {
+		t.Fatal(rows, err)
+	}
+	if rows, err := acme.InsertOrUpdateByPrimaryKey(&physics); !errors.Is(err, gormstarter.ErrTenantConflict) || rows != 0 {
+		t.Fatal(rows, err)
+	}
+	for _, onConflict := range []gormstarter.OnConflict[model.Course]{{}, {UpdateColumns: []gormstarter.Column[model.Course]{"tenant_id", "name"}}, {DoNothing: true}} {
+		if rows, err := acme.InsertOnConflict(&model.Course{ID: physics.ID, Name: "hijacked"}, onConflict); err != nil || rows != 0 {
+			t.Fatal(rows, err)
+		}
+	}
+	courses := []*model.Course{{ID: physics.ID, Name: "hijacked"}}
+	if rows, err := acme.InsertBatchOnConflict(&courses, gormstarter.OnConflict[model.Course]{}); err != nil || rows != 0 {
+		t.Fatal(rows, err)
+	}
+	if rows, err := acme.UpdateByMap(map[string]any{"name": "hijacked"}, map[string]any{"id": physics.ID}); err != nil || rows != 0 {
+		t.Fatal(rows, err)
+	}
+	if rows, err := acme.DeleteById(physics.ID); err != nil || rows != 0 {
+		t.Fatal(rows, err)
+	}
+	if found, err := globex.FindById(physics.ID); err != nil || found.Name != "physics" || found.TenantId != "globex" {
+		t.Fatal(found, err)
+	}
+
+	// 保存当前租户未变更的数据 不视为冲突
+	unchanged, err := acme.FindById(math.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = acme.InsertOrUpdateByPrimaryKey(unchanged); err != nil {
+		t.Fatal(err)
+	}
+	// 主键不存在时新增
+	if rows, err := acme.InsertOrUpdateByPrimaryKey(&model.Course{ID: 100, Name: "music"}); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if found, err := acme.FindById(100); err != nil || found.TenantId != "acme" {
+		t.Fatal(found, err)
+	}
+	if rows, err := acme.DeleteById(100); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+
+	// 冲突时仅更新当前租户的数据 且不修改租户
+	if rows, err := acme.InsertOnConflict(&model.Course{ID: math.ID, Name: "geometry", TenantId: "globex"}, gormstarter.OnConflict[model.Course]{}); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if found, err := acme.FindById(math.ID); err != nil || found.Name != "geometry" || found.TenantId != "acme" {
+		t.Fatal(found, err)
+	}
+
+	// 更新时不能修改租户
+	if rows, err := acme.UpdateByMap(map[string]any{"name": "algebra", "tenant_id": "globex"}, map[string]any{"id": math.ID}); err != nil || rows != 1 {
+		t.Fatal(rows, err)
+	}
+	if found, err := acme.FindById(math.ID); err != nil || found.Name != "algebra" || found.TenantId != "acme" {
+		t.Fatal(found, err)
+	}
+
+	// 跳过租户限定
+	if count, err := cm.WithoutTenant().CountByCond(&model.Course{}); err != nil || count != 3 {
+		t.Fatal(count, err)
+	}
+	admin := cm.WithContext(gormstarter.ContextWithoutTenant(context.Background()))
+	if rows, err := admin.DeleteByWhere("tenant_id = ?", "acme"); err != nil || rows != 2 {
+		t.Fatal(rows, err)
+	}
+	if count, err := globex.CountByCond(&model.Course{}); err != nil || count != 1 {
+		t.Fatal(count, err)
+	}
+}
@@ -0,0 +1,80 @@
+create table demo_student
+(
+    id          integer primary key autoincrement,
+    create_time datetime    default CURRENT_TIMESTAMP,
+    update_time datetime    default CURRENT_TIMESTAMP,
+    name        varchar(10) default '' not null,
+    sex         char        default '1' not null,
+    age         int         default 0 not null,
+    teacher_id  bigint
+);
+
+create table demo_teacher
+(
+    id          integer primary key autoincrement,
+    create_time datetime    default CURRENT_TIMESTAMP,
+    update_time datetime    default CURRENT_TIMESTAMP,
+    name        varchar(10) default '' not null,
+    sex         char        default '1' not null,
+    age         int         default 0 not null,
+    class_no    varchar(10),
+    version     int         default 0 not null
+);
+
+create table demo_teacher_class
+(
+    teacher_id bigint      not null,
+    class_no   int         not null,
+    remark     varchar(64) default '' not null,
+    deleted    boolean     default 0  not null,
+    primary key (teacher_id, class_no)
+);
+
+create table demo_grade
+(
+    student_no bigint primary key,
+    score      int default 0 not null
+);
+
+create table demo_visit
+(
+    page varchar(64) default '' not null
+);
+
+create table demo_member
+(
+    id         integer primary key autoincrement,
+    name       varchar(10) default '' not null,
+    sex        int         default 0 not null,
+    deleted_at datetime
+);
+
+create table demo_staff
+(
+    id         integer primary key autoincrement,
+    name       varchar(10) default '' not null,
+    sex        int         default 0 not null,
+    deleted_at datetime,
+    created_by varchar(32) default '' not null,
+    updated_by varchar(32) default '' not null,
+    deleted_by varchar(32) default '' not null
+);
+
+create table demo_course
+(
+    id        integer primary key autoincrement,
+    tenant_id varchar(32) not null,
+    name      varchar(64) default '' not null
+);
+
+create table audit_log
+(
+    id          integer primary key autoincrement,
+    table_name  varchar(64)  not null,
+    primary_key varchar(255) not null,
+    operation   varchar(16)  not null,
+    before_data text,
+    after_data  text,
+    operator    varchar(64)  default '' not null,
+    created_at  datetime
+);